@@ -1,56 +1,292 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
+	"github.com/olekukonko/tablewriter"
 	"github.com/ribbondz/gsv/cmd/utility"
+	"io"
 	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-func Count(path string, header bool) (nRow int) {
+// Count
+// lines, or quote-aware records, of each file.
+// a directory has the number of files and total size
+func Count(paths []string, header bool, mmap bool, records bool) (total int) {
 	var et utility.ElapsedTime
 	et.Start()
-	if !utility.FileIsExist(path) {
+	if len(paths) == 0 {
 		fmt.Println("File doest not exist. Try command 'gsv count --help'.")
 		return
 	}
+	for _, path := range paths {
+		if !utility.FileIsExist(path) {
+			fmt.Printf("File %s doest not exist. Try command 'gsv count --help'.\n", path)
+			return
+		}
+	}
+
 	// 1. is directory: count files in directory
-	if info, err := os.Stat(path); err == nil && info.IsDir() {
-		f, _ := os.Open(path)
-		list, _ := f.Readdir(-1)
-		fmt.Printf("Total files: %d\n", len(list))
+	if len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && info.IsDir() {
+			f, _ := os.Open(paths[0])
+			list, _ := f.Readdir(-1)
+			fmt.Printf("Total files: %d\n", len(list))
 
-		var t int64 = 0
-		for _, i := range list {
-			t += i.Size()
+			var t int64 = 0
+			for _, i := range list {
+				t += i.Size()
+			}
+			PrintFileSize(int(t))
+			f.Close()
+			return
 		}
-		PrintFileSize(int(t))
-		f.Close()
-		return
 	}
-	// 2. is file: count lines in file
-	r, err := os.Open(path)
-	defer r.Close()
-	utility.CheckErr(err)
-	var bufSize = MBBytes * 10 // 10MB
-	buf := make([]byte, bufSize)
-	for {
-		n, _ := r.Read(buf)
 
-		// count char '\n'
-		nRow += bytes.Count(buf[0:n], []byte{'\n'})
+	// 2. is file: count lines or records in each file
+	var table [][]string
+	for _, path := range paths {
+		n, err := CountFile(path, header, mmap, records)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		total += n
+		table = append(table, []string{path, strconv.Itoa(n)})
+	}
+	if len(paths) == 1 {
+		fmt.Printf("%d", total)
+	} else {
+		unit := "lines"
+		if records {
+			unit = "records"
+		}
+		t := tablewriter.NewWriter(os.Stdout)
+		t.SetHeader([]string{"file", unit})
+		t.SetAutoWrapText(false)
+		t.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT})
+		t.AppendBulk(table)
+		t.SetFooter([]string{"total", strconv.Itoa(total)})
+		t.Render()
+	}
+	et.EndAndPrint()
+	return
+}
 
-		// read finished.
-		if n < bufSize {
-			break
+// CountFile
+// lines, or quote-aware records, of a file, header excluded.
+// a last line without line terminator is counted.
+// the count is read from a fresh index if there is one,
+// otherwise byte ranges of the file, or of its memory mapping, are counted in parallel.
+// records of a file with unbalanced quotes are counted again by a record scanner
+func CountFile(path string, header bool, mmap bool, records bool) (n int, err error) {
+	defer func() {
+		if header && n > 0 {
+			n--
 		}
+	}()
+	if idx, fresh := utility.LoadIndex(path); fresh {
+		if records {
+			return idx.Records, nil
+		}
+		return idx.Lines, nil
+	}
+	size := int64(utility.FileSize(path))
+	if size == 0 {
+		return 0, nil
+	}
+
+	// read a byte range
+	var read func(start, end int64, handle func(b []byte)) error
+	if mmap {
+		if m, err := utility.OpenMapped(path); err == nil {
+			defer m.Close()
+			read = func(start, end int64, handle func(b []byte)) error {
+				handle(m.Data[start:end])
+				return nil
+			}
+		}
+	}
+	if read == nil {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		read = func(start, end int64, handle func(b []byte)) error {
+			buf := make([]byte, MBBytes)
+			r := io.NewSectionReader(f, start, end-start)
+			for {
+				n, err := r.Read(buf)
+				handle(buf[:n])
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// count byte ranges in parallel
+	workers := runtime.NumCPU()
+	rangeSize := size/int64(workers) + 1
+	counts := make([]utility.RangeCount, workers)
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			start, end := int64(i)*rangeSize, int64(i+1)*rangeSize
+			if end > size {
+				end = size
+			}
+			if start >= end {
+				errs <- nil
+				return
+			}
+			errs <- read(start, end, func(b []byte) {
+				counts[i].Add(b, records)
+			})
+		}(i)
+	}
+	for i := 0; i < workers; i++ {
+		if e := <-errs; e != nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, stray := utility.MergeRangeCounts(counts, records)
+	if stray {
+		// unbalanced quotes end records as the record scanner and index do
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		return utility.CountRecords(f)
+	}
+	return n, nil
+}
+
+// CountFilter
+// rows satisfying filter, in total, or by values of column by.
+// batches of rows are filtered and counted in parallel, only the counts are kept
+func CountFilter(file string, header bool, sep string, filterPara string, by string, mmap bool, ragged *utility.Ragged) {
+	var et utility.ElapsedTime
+	et.Start()
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv count --help'.")
+		return
 	}
+
+	// column names
+	columnN := ColumnN(file, sep)
+	var names []string
 	if header {
-		nRow--
+		names = strings.Split(string(utility.HeaderBytes(file)), sep)
+	} else {
+		for i := 0; i < columnN; i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+
+	// filters
+	filter, err := utility.NewFilter(filterPara, columnN)
+	if err != nil {
+		fmt.Print("Filter syntax error. Try command 'gsv count --help'.")
+		return
+	}
+
+	// group-by column
+	col := -1
+	if by != "" {
+		if col, err = utility.ColumnIndex(by, names); err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	}
+
+	total := 0
+	counts := make(map[string]int)
+	release, err := utility.ParallelRead(file, header, mmap, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return countFilterRows(filter, rows, sep, col)
+	}, func(r interface{}, n int) {
+		for k, c := range r.(map[string]int) {
+			counts[k] += c
+			total += c
+		}
+	})
+	defer release()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if col < 0 {
+		fmt.Printf("%d", total)
+	} else {
+		printGroupCounts(counts, names[col], total)
 	}
-	fmt.Printf("%d", nRow)
 	et.EndAndPrint()
-	return
+}
+
+// countFilterRows
+// counts of rows satisfying filter by values of column col,
+// all rows are counted with key "" if col is -1
+func countFilterRows(f *utility.Filter, rows []string, sep string, col int) map[string]int {
+	r := make(map[string]int)
+	var fields []string // reused across rows
+	for _, row := range rows {
+		fields = utility.SplitInto(row, sep, fields)
+		if !f.FilterOneRowSatisfy(fields) {
+			continue
+		}
+		key := ""
+		if col >= 0 {
+			key = FreqNullLabel
+			if col < len(fields) {
+				if v := utility.Normalize(fields[col]); !utility.IsNullValue(v) {
+					key = v
+				}
+			}
+		}
+		r[key]++
+	}
+	return r
+}
+
+// printGroupCounts
+// counts by value in descending order, with percentages of total
+func printGroupCounts(counts map[string]int, name string, total int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	var table [][]string
+	for _, k := range keys {
+		table = append(table, []string{k, strconv.Itoa(counts[k]), percentString(counts[k], total)})
+	}
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetHeader([]string{name, "count", "percent"})
+	t.SetAutoWrapText(false)
+	t.SetAutoFormatHeaders(false)
+	t.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT})
+	t.AppendBulk(table)
+	t.SetFooter([]string{"total", strconv.Itoa(total), ""})
+	t.Render()
 }
 
 func PrintFileSize(c int) {
@@ -2,20 +2,32 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/gob"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
 	"github.com/ribbondz/gsv/cmd/utility"
+	"hash/fnv"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
-func Frequency(file string, header bool, sep string, colPara utility.ColArgs, out bool, ascending bool, limit int) {
+const (
+	FreqSortCount   = "count"
+	FreqSortValue   = "value"   // lexical order
+	FreqSortNumeric = "numeric" // numeric order, non-numeric values last
+	FreqNullLabel   = "(null)"  // bucket of null values: "", NA, Na, na, Null, NULL
+	FreqOtherLabel  = "(other)" // tail of values dropped by limit
+	FreqEntryBytes  = 64        // approximate bytes of a frequency map entry, excluding the value
+	FreqMemory      = 1024      // default memory budget in MB, also of approximate counting without a limit
+)
+
+func Frequency(file string, header bool, sep string, colPara utility.ColArgs, out bool, ascending bool, limit int, combine bool, sortBy string, approx bool, memory int, bin int, mmap bool, ragged *utility.Ragged) {
 	var et utility.ElapsedTime
 	et.Start()
 	// check file existence
@@ -23,101 +35,238 @@ func Frequency(file string, header bool, sep string, colPara utility.ColArgs, ou
 		fmt.Print("File does not exist. Try command 'gsv frequency --help'.")
 		return
 	}
+	if sortBy != FreqSortCount && sortBy != FreqSortValue && sortBy != FreqSortNumeric {
+		fmt.Print("Sort option should be count, value or numeric. Try command 'gsv frequency --help'.")
+		return
+	}
 
 	columnN := ColumnN(file, sep)                    // how many columns
 	col := utility.AllIncludedCols(colPara, columnN) // all included columns []int
 
-	// file processing
-	f, _ := os.Open(file)
-	defer f.Close()
-	br := bufio.NewScanner(f)
-
-	// column names and header drop
+	// column names
 	var names []string
 	if header {
-		br.Scan()
-		names = strings.Split(br.Text(), sep)
+		names = strings.Split(string(utility.HeaderBytes(file)), sep)
 	} else {
 		for i := 0; i < columnN; i++ {
 			names = append(names, "col_"+strconv.Itoa(i+1))
 		}
 	}
-	jobs := make(chan []string, 20)            // batch rows
-	results := make(chan []map[string]int, 20) // batch processed result
-	wg := &sync.WaitGroup{}                    // wait for all batches to be processed
-	freq := freqMapInit(col)                   // data structure to save frequency table, []map[string]int
-	// worker, process batch rows
-	for i := 0; i < runtime.NumCPU(); i++ {
-		go func() {
-			for job := range jobs {
-				results <- processRows(job, sep, col)
-			}
-		}()
+	// bin edges of numeric columns, nil for other columns
+	var edges [][]float64
+	if bin > 0 {
+		var err error
+		if edges, err = freqBinEdges(file, header, sep, col, bin); err != nil {
+			fmt.Println(err.Error())
+			return
+		}
 	}
 
-	// collect batch result, and merge it into main result
-	go func() {
-		for result := range results {
-			freq = MergeMapList(freq, result)
-			wg.Done()
+	freq := freqMapInit(col) // data structure to save frequency table, []map[string]int
+	if combine {
+		freq = []map[string]int{make(map[string]int)} // one map, the key is a tuple of selected columns
+	}
+	totals := make([]int, len(freq)) // total count of each column
+	memoryBytes := memory * MBBytes  // memory budget
+	sizeBytes := 0                   // approximate memory of frequency maps
+	spill := &freqSpill{}            // spill files, used when exact counting exceeds memory budget
+	// approximate counting, one sketch for each column, or one for the combination.
+	// the budget is shared by sketches, so there is no unlimited approximate counting
+	var sketches []*utility.FreqSketch
+	if approx {
+		if memory <= 0 {
+			memoryBytes = FreqMemory * MBBytes
 		}
-	}()
-
-	N := 0              // total number of rows
-	n := 0              // batch number of rows
-	batch := []string{} //batch holder
-	for br.Scan() {
-		N++
-		batch = append(batch, br.Text())
-		n++
-		if n > BatchRowsPerStat { // 2000 rows per batch
-			wg.Add(1)
-			jobs <- batch
-			n = 0
-			batch = []string{}
+		sketchN := 0
+		for i := range freq {
+			if freq[i] != nil {
+				sketchN++
+			}
+		}
+		for i := range freq {
+			if freq[i] != nil {
+				sketches = append(sketches, utility.NewFreqSketch(memoryBytes/sketchN))
+			} else {
+				sketches = append(sketches, nil)
+			}
 		}
 	}
+	// byte ranges of file are read and processed in parallel, results are merged in order
+	N := 0 // total number of rows
+	release, err := utility.ParallelRead(file, header, mmap, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		if combine {
+			return processRowsCombined(rows, sep, col, edges)
+		}
+		return processRows(rows, sep, col, edges)
+	}, func(r interface{}, n int) {
+		N += n
+		result := r.([]map[string]int)
+		for i, m := range result {
+			for _, v := range m {
+				totals[i] += v
+			}
+		}
+		if approx {
+			for i, m := range result {
+				for k, v := range m {
+					sketches[i].Add(k, v)
+				}
+			}
+		} else {
+			sizeBytes += mergeMapListSized(freq, result)
+			// spill exact counts to disk when over memory budget
+			if memory > 0 && sizeBytes > memoryBytes {
+				spill.write(freq)
+				freq = freqMapInit(col)
+				if combine {
+					freq = []map[string]int{make(map[string]int)}
+				}
+				sizeBytes = 0
+			}
+		}
+	})
+	// values are views of the mapping until frequency tables are shown
+	defer release()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
 
-	if len(batch) > 0 {
-		wg.Add(1)
-		jobs <- batch
+	// approximate counts are estimated from sketches,
+	// spilled exact counts are merged bucket by bucket, keeping candidates within limit
+	var errs []map[string]int
+	if approx {
+		errs = make([]map[string]int, len(freq))
+		for i, s := range sketches {
+			if s != nil {
+				freq[i], errs[i] = s.Estimates()
+			}
+		}
+	} else if spill.dir != "" {
+		spill.write(freq)
+		fmt.Printf("Frequency tables exceed %dMB, spilled to disk.\n", memory)
+		freq, err = spill.merge(len(freq), sortBy, ascending, limit)
+		spill.remove()
+		if err != nil {
+			fmt.Println("Error reading spilled frequency tables: " + err.Error())
+			return
+		}
 	}
-	close(jobs)
 
-	// wait all batch result to be processed
-	wg.Wait()
 	// generate freq table ([][]string) from results ([]map[string]int)
 	// apply ascending option
 	// apply limit option
-	table := GenerateFreqTable(freq, names, ascending, limit)
+	var table [][]string
+	tableHeader := []string{"col", "value", "count", "percent", "cumulative_percent"}
+	if combine {
+		table = GenerateCombinedFreqTable(freq[0], totals[0], errs, sep, len(col), sortBy, ascending, limit)
+		tableHeader = []string{}
+		for _, c := range col {
+			tableHeader = append(tableHeader, names[c])
+		}
+		tableHeader = append(tableHeader, "count", "percent", "cumulative_percent")
+	} else {
+		table = GenerateFreqTable(freq, totals, errs, names, sortBy, ascending, limit)
+	}
+	// error of approximate counts, true count is in [count-error, count]
+	if approx {
+		tableHeader = append(tableHeader, "error")
+	}
 	// apply out option
 	if out {
-		table = utility.PrependStringSlice(table, []string{"col", "value", "count"})
+		table = utility.PrependStringSlice(table, tableHeader)
 		outFile := OutFilename(file)
 		utility.SaveFile(outFile, table)
 		fmt.Println("Frequency table saved to: ", outFile)
 	} else {
-		PrintFreqTable(table, N)
+		PrintFreqTable(tableHeader, table, N)
 		if limit > 0 {
 			fmt.Println("Limit: ", limit)
 		}
 	}
+	if approx {
+		for i, s := range sketches {
+			if s != nil {
+				name := "combined"
+				if !combine {
+					name = names[i]
+				}
+				fmt.Printf("Approximate counts of %s: error at most %d with 99%% probability\n", name, s.ErrorBound())
+			}
+		}
+	}
 	et.EndAndPrint()
 }
 
 // process batch rows
-func processRows(rows []string, sep string, col []int) []map[string]int {
+func processRows(rows []string, sep string, col []int, edges [][]float64) []map[string]int {
 	r := freqMapInit(col)
+	var fields []string // reused across rows
 	for _, row := range rows {
-		for i, field := range strings.Split(row, sep) {
-			if utility.SliceContainsInt(col, i) {
-				r[i][field]++
+		fields = utility.NormalizeFields(utility.SplitInto(row, sep, fields))
+		for i, field := range fields {
+			if i < len(r) && r[i] != nil {
+				r[i][freqValue(field, i, edges)]++
 			}
 		}
 	}
 	return r
 }
 
+// value counted in frequency table,
+// null values are counted in one bucket, numeric values are binned if edges are set
+func freqValue(field string, i int, edges [][]float64) string {
+	if utility.IsNullValue(field) {
+		return FreqNullLabel
+	}
+	if i < len(edges) && edges[i] != nil {
+		if v, err := strconv.ParseFloat(field, 64); err == nil {
+			return utility.BinLabel(edges[i], utility.BinIndex(edges[i], v))
+		}
+	}
+	return field
+}
+
+// freqBinEdges
+// equal width bin edges of selected numeric columns,
+// from min and max computed by column statistics
+func freqBinEdges(file string, header bool, sep string, col []int, bin int) ([][]float64, error) {
+	stat, _, _, err := ColumnStats(file, header, sep)
+	if err != nil {
+		return nil, err
+	}
+	edges := make([][]float64, len(stat))
+	for _, c := range col {
+		if c >= len(stat) {
+			continue
+		}
+		if min, max, ok := stat[c].NumericRange(); ok {
+			edges[c] = utility.EqualWidthEdges(min, max, bin)
+		}
+	}
+	return edges, nil
+}
+
+// process batch rows, counting tuples of selected columns,
+// the key is the selected fields joined by separator
+func processRowsCombined(rows []string, sep string, col []int, edges [][]float64) []map[string]int {
+	r := make(map[string]int)
+	var fields []string // reused across rows
+	key := make([]string, len(col))
+	for _, row := range rows {
+		fields = utility.NormalizeFields(utility.SplitInto(row, sep, fields))
+		for i, c := range col {
+			if c >= len(fields) {
+				key[i] = FreqNullLabel
+			} else {
+				key[i] = freqValue(fields[c], c, edges)
+			}
+		}
+		r[strings.Join(key, sep)]++
+	}
+	return []map[string]int{r}
+}
+
 // MergeMapList
 // batch frequency tables are merged into main table sequentially
 func MergeMapList(l1, l2 []map[string]int) []map[string]int {
@@ -130,15 +279,35 @@ func MergeMapList(l1, l2 []map[string]int) []map[string]int {
 	return l1
 }
 
+// mergeMapListSized
+// merge batch frequency tables like MergeMapList,
+// returns approximate bytes of new keys
+func mergeMapListSized(l1, l2 []map[string]int) (size int) {
+	for i := range l1 {
+		a, b := l1[i], l2[i]
+		for k, v := range b {
+			if _, ok := a[k]; !ok {
+				size += len(k) + FreqEntryBytes
+			}
+			a[k] += v
+		}
+	}
+	return
+}
+
 // initial frequency table to a list of N maps,
-// each map for a column,
+// each map for a selected column, nil for other columns,
 // the map records column value (key) and counts (value)
 func freqMapInit(includeColumn []int) []map[string]int {
-	var r []map[string]int
-	maxColumnIndex := includeColumn[len(includeColumn)-1]
-	for i := 0; i <= maxColumnIndex; i++ {
-		m := make(map[string]int)
-		r = append(r, m)
+	maxColumnIndex := 0
+	for _, c := range includeColumn {
+		if c > maxColumnIndex {
+			maxColumnIndex = c
+		}
+	}
+	r := make([]map[string]int, maxColumnIndex+1)
+	for _, c := range includeColumn {
+		r[c] = make(map[string]int)
 	}
 	return r
 }
@@ -147,49 +316,153 @@ func freqMapInit(includeColumn []int) []map[string]int {
 // transform list of N maps to a frequency table,
 // the table has structure [][]string
 // the table can be feed into a tablewriter to print in stdout, or to be saved into a file
-// the function also sort records according to -ascending flag,
-// default to descending order
-func GenerateFreqTable(freq []map[string]int, names []string, ascending bool, limit int) (r [][]string) {
+// the function also sort records according to -sort and -ascending flags,
+// default to descending order of count
+// errs of approximate counts, if not nil, are appended as the last column
+func GenerateFreqTable(freq []map[string]int, totals []int, errs []map[string]int, names []string, sortBy string, ascending bool, limit int) (r [][]string) {
 	for i, l := range freq {
-		var one [][]string
-		for k, v := range l {
-			one = append(one, []string{names[i], k, strconv.Itoa(v)})
+		// unselected columns have empty maps
+		if len(l) == 0 {
+			continue
+		}
+		for _, row := range freqRows(l, totals[i], sortBy, ascending, limit) {
+			if errs != nil {
+				row = append(row, freqErrorString(errs[i], row[0]))
+			}
+			r = append(r, append([]string{names[i]}, row...))
 		}
+	}
+	return
+}
 
-		// apply ascending option
-		if ascending {
-			sort.Slice(one, func(i, j int) bool {
-				a, _ := strconv.Atoi(one[i][2])
-				b, _ := strconv.Atoi(one[j][2])
-				return a < b
-			})
+// GenerateCombinedFreqTable
+// transform the map of value tuples to a frequency table,
+// each selected column is displayed as a separate column, followed by count
+func GenerateCombinedFreqTable(freq map[string]int, total int, errs []map[string]int, sep string, colN int, sortBy string, ascending bool, limit int) (r [][]string) {
+	for _, row := range freqRows(freq, total, sortBy, ascending, limit) {
+		if errs != nil {
+			row = append(row, freqErrorString(errs[0], row[0]))
+		}
+		var fields []string
+		if row[0] == FreqOtherLabel {
+			fields = make([]string, colN)
+			fields[0] = FreqOtherLabel
 		} else {
-			sort.Slice(one, func(i, j int) bool {
-				a, _ := strconv.Atoi(one[i][2])
-				b, _ := strconv.Atoi(one[j][2])
-				return a > b
-			})
+			fields = strings.Split(row[0], sep)
 		}
+		r = append(r, append(fields, row[1:]...))
+	}
+	return
+}
+
+type freqItem struct {
+	value  string
+	count  int
+	number float64
+	isNum  bool
+}
 
-		// apply limit option
-		if limit > 0 && len(one) >= limit {
-			one = one[0:limit]
+// sortFreqItems
+// values sorted by count, value (lexical order) or numeric order of value
+func sortFreqItems(freq map[string]int, sortBy string, ascending bool) (items []freqItem) {
+	for k, v := range freq {
+		it := freqItem{value: k, count: v}
+		if sortBy == FreqSortNumeric {
+			it.number, it.isNum = freqSortNumber(k)
 		}
+		items = append(items, it)
+	}
 
-		t := make([][]string, len(r)+len(one))
-		copy(t, r)
-		copy(t[len(r):], one)
-		r = t
+	// less in ascending order, ties are ordered by value
+	less := func(a, b freqItem) bool {
+		switch sortBy {
+		case FreqSortValue:
+			return a.value < b.value
+		case FreqSortNumeric:
+			// non-numeric values follow numeric values
+			if a.isNum != b.isNum {
+				return a.isNum
+			}
+			if a.isNum && a.number != b.number {
+				return a.number < b.number
+			}
+			return a.value < b.value
+		}
+		if a.count == b.count {
+			return a.value > b.value
+		}
+		return a.count < b.count
 	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		// null bucket is always last when sorting by value
+		if sortBy != FreqSortCount && (a.value == FreqNullLabel) != (b.value == FreqNullLabel) {
+			return b.value == FreqNullLabel
+		}
+		if ascending {
+			return less(a, b)
+		}
+		return less(b, a)
+	})
+	return
+}
+
+// numeric value to sort by, bins are sorted by lower edge
+func freqSortNumber(v string) (float64, bool) {
+	switch {
+	case strings.HasPrefix(v, "< "):
+		return math.Inf(-1), true
+	case strings.HasPrefix(v, "> "):
+		return math.Inf(1), true
+	case strings.HasPrefix(v, "[") && strings.Contains(v, ", "):
+		v = v[1:strings.Index(v, ", ")]
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	return n, err == nil
+}
 
+// freqRows
+// sorted rows of value, count, percent and cumulative percent,
+// the rest of total not shown within limit is summarized in an (other) row
+func freqRows(freq map[string]int, total int, sortBy string, ascending bool, limit int) (r [][]string) {
+	items := sortFreqItems(freq, sortBy, ascending)
+
+	// apply limit option
+	if limit > 0 && len(items) > limit {
+		items = items[0:limit]
+	}
+
+	cumulative := 0
+	for _, it := range items {
+		cumulative += it.count
+		r = append(r, []string{it.value, strconv.Itoa(it.count), percentString(it.count, total), percentString(cumulative, total)})
+	}
+	if other := total - cumulative; other > 0 {
+		r = append(r, []string{FreqOtherLabel, strconv.Itoa(other), percentString(other, total), percentString(total, total)})
+	}
 	return
 }
 
+// error of an approximate count, unknown for (other) row
+func freqErrorString(errs map[string]int, value string) string {
+	if e, ok := errs[value]; ok {
+		return strconv.Itoa(e)
+	}
+	return "-"
+}
+
+func percentString(n, total int) string {
+	if total == 0 {
+		return "0.00"
+	}
+	return strconv.FormatFloat(float64(n)*100/float64(total), 'f', 2, 64)
+}
+
 // PrintFreqTable
 // use tablewriter to print frequency table to stdout
-func PrintFreqTable(freq [][]string, totalN int) {
+func PrintFreqTable(header []string, freq [][]string, totalN int) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"col", "value", "count"})
+	table.SetHeader(header)
 	table.SetBorder(true)
 	table.AppendBulk(freq)
 	table.SetAlignment(tablewriter.ALIGN_RIGHT)
@@ -206,3 +479,103 @@ func OutFilename(file string) string {
 	timeStr := time.Now().Format("20060102150405")
 	return filepath.Join(wd, file+"-frequency-table-"+timeStr+".txt")
 }
+
+// freqSpill
+// exact frequency tables spilled to disk, bucketed by hash of value,
+// so that each bucket holds the complete count of its values
+type freqSpill struct {
+	dir      string
+	files    [][]*os.File // column, bucket
+	writers  [][]*bufio.Writer
+	encoders [][]*gob.Encoder
+}
+
+func (s *freqSpill) write(freq []map[string]int) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "gsv-frequency-")
+		utility.CheckErr(err)
+		s.dir = dir
+		s.files = make([][]*os.File, len(freq))
+		s.writers = make([][]*bufio.Writer, len(freq))
+		s.encoders = make([][]*gob.Encoder, len(freq))
+	}
+	for i, m := range freq {
+		if len(m) == 0 {
+			continue
+		}
+		if s.files[i] == nil {
+			for j := 0; j < SpillBuckets; j++ {
+				f, err := os.Create(filepath.Join(s.dir, strconv.Itoa(i)+"-"+strconv.Itoa(j)+".gob"))
+				utility.CheckErr(err)
+				w := bufio.NewWriter(f)
+				s.files[i] = append(s.files[i], f)
+				s.writers[i] = append(s.writers[i], w)
+				s.encoders[i] = append(s.encoders[i], gob.NewEncoder(w))
+			}
+		}
+		buckets := make([]map[string]int, SpillBuckets)
+		for k, v := range m {
+			h := fnv.New32a()
+			h.Write([]byte(k))
+			b := h.Sum32() % SpillBuckets
+			if buckets[b] == nil {
+				buckets[b] = make(map[string]int)
+			}
+			buckets[b][k] = v
+		}
+		for j, b := range buckets {
+			if b != nil {
+				utility.CheckErr(s.encoders[i][j].Encode(b))
+			}
+		}
+	}
+}
+
+// merge
+// merge spilled counts bucket by bucket,
+// only the first limit values of each bucket can be within the overall limit.
+// a bucket which cannot be decoded to its end is an error, not a truncated bucket
+func (s *freqSpill) merge(n int, sortBy string, ascending bool, limit int) ([]map[string]int, error) {
+	r := make([]map[string]int, n)
+	for i := range s.files {
+		if s.files[i] == nil {
+			continue
+		}
+		r[i] = make(map[string]int)
+		for j, f := range s.files[i] {
+			utility.CheckErr(s.writers[i][j].Flush())
+			_, err := f.Seek(0, 0)
+			utility.CheckErr(err)
+			dec := gob.NewDecoder(bufio.NewReader(f))
+			bucket := make(map[string]int)
+			for {
+				var m map[string]int
+				if err := dec.Decode(&m); err == io.EOF {
+					break
+				} else if err != nil {
+					return nil, err
+				}
+				for k, v := range m {
+					bucket[k] += v
+				}
+			}
+			items := sortFreqItems(bucket, sortBy, ascending)
+			if limit > 0 && len(items) > limit {
+				items = items[0:limit]
+			}
+			for _, it := range items {
+				r[i][it.value] = it.count
+			}
+		}
+	}
+	return r, nil
+}
+
+func (s *freqSpill) remove() {
+	for _, files := range s.files {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	os.RemoveAll(s.dir)
+}
@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+const (
+	SpillBuckets = 64 // number of spill files when groups exceed memory
+)
+
+// AggState
+// partial aggregate of one column in a group,
+// fields are exported to be spilled to disk with gob
+type AggState struct {
+	N        int     // not-null values
+	NumN     int     // numeric values
+	Sum      float64 // sum of numeric values
+	Numeric  bool    // all not-null values are numeric
+	NumMin   string  // min and max in numeric order
+	NumMax   string
+	NumMinF  float64
+	NumMaxF  float64
+	StrMin   string // min and max in lexical order
+	StrMax   string
+	Distinct map[string]bool
+}
+
+// GroupAgg
+// partial aggregates of a group
+type GroupAgg struct {
+	Key   string // group-by fields joined by separator
+	Count int    // number of rows
+	Aggs  []AggState
+}
+
+func GroupBy(file string, header bool, sep string, keyPara string, aggPara string, out bool, maxGroups int, ragged *utility.Ragged) {
+	var et utility.ElapsedTime
+	et.Start()
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv groupby --help'.")
+		return
+	}
+
+	// file processing
+	f, _ := os.Open(file)
+	defer f.Close()
+	br := bufio.NewScanner(f)
+
+	// column names and header drop
+	columnN := ColumnN(file, sep)
+	var names []string
+	if header {
+		br.Scan()
+		names = strings.Split(br.Text(), sep)
+	} else {
+		for i := 0; i < columnN; i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+
+	// group-by columns and aggregations
+	keys, err := utility.ParseColNames(keyPara, names)
+	if err != nil {
+		fmt.Printf("Group-by column syntax error: %s. Try command 'gsv groupby --help'.\n", err.Error())
+		return
+	}
+	aggs, err := utility.ParseAggArg(aggPara, names)
+	if err != nil {
+		fmt.Printf("Aggregation syntax error: %s. Try command 'gsv groupby --help'.\n", err.Error())
+		return
+	}
+
+	groups := make(map[string]*GroupAgg) // merged partial aggregates
+	spill := &groupSpill{}               // spill files, used when groups exceed maxGroups
+	N := 0                               // total number of rows
+	// byte ranges of file are read and processed in parallel, results are merged in order,
+	// all groups are spilled to disk when there are too many
+	release, err := utility.ParallelRead(file, header, false, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return aggProcessRows(rows, sep, keys, aggs)
+	}, func(result interface{}, n int) {
+		N += n
+		groups = mergeGroupAgg(groups, result.(map[string]*GroupAgg))
+		if maxGroups > 0 && len(groups) > maxGroups {
+			spill.write(groups)
+			groups = make(map[string]*GroupAgg)
+		}
+	})
+	defer release()
+	if err != nil {
+		spill.remove()
+		fmt.Println(err.Error())
+		return
+	}
+
+	// table header
+	var tableHeader []string
+	for _, k := range keys {
+		tableHeader = append(tableHeader, names[k])
+	}
+	for _, a := range aggs {
+		tableHeader = append(tableHeader, a.Name)
+	}
+
+	// output rows, sorted by group key.
+	// after spilling, rows are sorted within each spill bucket.
+	var (
+		table   [][]string
+		groupN  = 0
+		outFile string
+		outF    *os.File
+		outW    *csv.Writer
+	)
+	if out {
+		outFile = utility.OutFilename(file, "groupby")
+		outF, err = os.OpenFile(outFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		utility.CheckErr(err)
+		outW = csv.NewWriter(outF)
+		outW.Write(tableHeader)
+	}
+	emit := func(m map[string]*GroupAgg) {
+		groupN += len(m)
+		if out {
+			outW.WriteAll(groupAggTable(m, sep, aggs))
+		} else {
+			table = append(table, groupAggTable(m, sep, aggs)...)
+		}
+	}
+	if spill.dir == "" {
+		emit(groups)
+	} else {
+		spill.write(groups)
+		fmt.Printf("Groups exceed %d, spilled to disk.\n", maxGroups)
+		err = spill.mergeEach(emit)
+		spill.remove()
+		if err != nil {
+			if out {
+				// no partial output is left behind
+				outF.Close()
+				os.Remove(outFile)
+			}
+			fmt.Println("Error reading spilled groups: " + err.Error())
+			return
+		}
+	}
+
+	if out {
+		outW.Flush()
+		outF.Close()
+		fmt.Println("Aggregation table saved to: ", outFile)
+	} else {
+		t := tablewriter.NewWriter(os.Stdout)
+		t.SetHeader(tableHeader)
+		t.SetBorder(true)
+		t.SetAutoFormatHeaders(false)
+		t.AppendBulk(table)
+		t.SetAlignment(tablewriter.ALIGN_RIGHT)
+		t.Render()
+	}
+	fmt.Printf("Total records: %d, total groups: %d\n", N, groupN)
+	et.EndAndPrint()
+}
+
+// partial aggregates of batch rows
+func aggProcessRows(rows []string, sep string, keys []int, aggs []utility.Agg) map[string]*GroupAgg {
+	r := make(map[string]*GroupAgg)
+	for _, row := range rows {
+		fields := utility.NormalizeFields(strings.Split(row, sep))
+		k := groupKey(fields, keys, sep)
+		g, ok := r[k]
+		if !ok {
+			g = newGroupAgg(k, aggs)
+			r[k] = g
+		}
+		g.Count++
+		for i, a := range aggs {
+			if a.Col < 0 || a.Col >= len(fields) || utility.IsNullValue(fields[a.Col]) {
+				continue
+			}
+			g.Aggs[i].add(fields[a.Col])
+		}
+	}
+	return r
+}
+
+func newGroupAgg(key string, aggs []utility.Agg) *GroupAgg {
+	g := &GroupAgg{Key: key, Aggs: make([]AggState, len(aggs))}
+	for i, a := range aggs {
+		g.Aggs[i].Numeric = true
+		if a.Func == utility.AggCountDistinct {
+			g.Aggs[i].Distinct = make(map[string]bool)
+		}
+	}
+	return g
+}
+
+// add a not-null value
+func (s *AggState) add(v string) {
+	if s.N == 0 || v < s.StrMin {
+		s.StrMin = v
+	}
+	if s.N == 0 || v > s.StrMax {
+		s.StrMax = v
+	}
+	s.N++
+	if s.Distinct != nil {
+		s.Distinct[v] = true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		s.Numeric = false
+		return
+	}
+	if s.NumN == 0 || f < s.NumMinF {
+		s.NumMin, s.NumMinF = v, f
+	}
+	if s.NumN == 0 || f > s.NumMaxF {
+		s.NumMax, s.NumMaxF = v, f
+	}
+	s.NumN++
+	s.Sum += f
+}
+
+// merge partial aggregate b into s
+func (s *AggState) merge(b *AggState) {
+	if b.N > 0 && (s.N == 0 || b.StrMin < s.StrMin) {
+		s.StrMin = b.StrMin
+	}
+	if b.N > 0 && (s.N == 0 || b.StrMax > s.StrMax) {
+		s.StrMax = b.StrMax
+	}
+	if b.NumN > 0 && (s.NumN == 0 || b.NumMinF < s.NumMinF) {
+		s.NumMin, s.NumMinF = b.NumMin, b.NumMinF
+	}
+	if b.NumN > 0 && (s.NumN == 0 || b.NumMaxF > s.NumMaxF) {
+		s.NumMax, s.NumMaxF = b.NumMax, b.NumMaxF
+	}
+	s.N += b.N
+	s.NumN += b.NumN
+	s.Sum += b.Sum
+	s.Numeric = s.Numeric && b.Numeric
+	if len(b.Distinct) > 0 {
+		if s.Distinct == nil {
+			s.Distinct = make(map[string]bool)
+		}
+		for k := range b.Distinct {
+			s.Distinct[k] = true
+		}
+	}
+}
+
+// value of the aggregation, empty when the group has no value
+func (s *AggState) value(a utility.Agg, count int) string {
+	switch a.Func {
+	case utility.AggCount:
+		if a.Col < 0 {
+			return strconv.Itoa(count)
+		}
+		return strconv.Itoa(s.N)
+	case utility.AggCountDistinct:
+		return strconv.Itoa(len(s.Distinct))
+	case utility.AggSum:
+		return strconv.FormatFloat(s.Sum, 'f', -1, 64)
+	case utility.AggMean:
+		if s.NumN == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(s.Sum/float64(s.NumN), 'f', 4, 64)
+	case utility.AggMin:
+		if s.Numeric {
+			return s.NumMin
+		}
+		return s.StrMin
+	case utility.AggMax:
+		if s.Numeric {
+			return s.NumMax
+		}
+		return s.StrMax
+	}
+	return ""
+}
+
+// merge batch partial aggregates into main result
+func mergeGroupAgg(dst, s map[string]*GroupAgg) map[string]*GroupAgg {
+	for k, b := range s {
+		a, ok := dst[k]
+		if !ok {
+			dst[k] = b
+			continue
+		}
+		a.Count += b.Count
+		for i := range a.Aggs {
+			a.Aggs[i].merge(&b.Aggs[i])
+		}
+	}
+	return dst
+}
+
+// rows of aggregation table, sorted by group key
+func groupAggTable(groups map[string]*GroupAgg, sep string, aggs []utility.Agg) (r [][]string) {
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g := groups[k]
+		row := strings.Split(k, sep)
+		for i, a := range aggs {
+			row = append(row, g.Aggs[i].value(a, g.Count))
+		}
+		r = append(r, row)
+	}
+	return
+}
+
+// groupSpill
+// partial aggregates spilled to disk, bucketed by hash of group key,
+// so that each bucket can be merged in memory independently
+type groupSpill struct {
+	dir      string
+	files    []*os.File
+	writers  []*bufio.Writer
+	encoders []*gob.Encoder
+}
+
+func (s *groupSpill) write(groups map[string]*GroupAgg) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "gsv-groupby-")
+		utility.CheckErr(err)
+		s.dir = dir
+		for i := 0; i < SpillBuckets; i++ {
+			f, err := os.Create(filepath.Join(dir, strconv.Itoa(i)+".gob"))
+			utility.CheckErr(err)
+			w := bufio.NewWriter(f)
+			s.files = append(s.files, f)
+			s.writers = append(s.writers, w)
+			s.encoders = append(s.encoders, gob.NewEncoder(w))
+		}
+	}
+	for k, g := range groups {
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		utility.CheckErr(s.encoders[h.Sum32()%SpillBuckets].Encode(g))
+	}
+}
+
+// merge partial aggregates in each bucket, and hand over the merged groups.
+// a bucket which cannot be decoded to its end is an error, not a truncated bucket
+func (s *groupSpill) mergeEach(handle func(map[string]*GroupAgg)) error {
+	for i, f := range s.files {
+		utility.CheckErr(s.writers[i].Flush())
+		_, err := f.Seek(0, 0)
+		utility.CheckErr(err)
+		dec := gob.NewDecoder(bufio.NewReader(f))
+		groups := make(map[string]*GroupAgg)
+		for {
+			var g GroupAgg
+			if err := dec.Decode(&g); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if a, ok := groups[g.Key]; ok {
+				a.Count += g.Count
+				for j := range a.Aggs {
+					a.Aggs[j].merge(&g.Aggs[j])
+				}
+			} else {
+				groups[g.Key] = &g
+			}
+		}
+		handle(groups)
+	}
+	return nil
+}
+
+func (s *groupSpill) remove() {
+	for _, f := range s.files {
+		f.Close()
+	}
+	os.RemoveAll(s.dir)
+}
@@ -1,30 +1,56 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/ribbondz/gsv/cmd/utility"
 	"os"
+	"strings"
 )
 
-func Head(path string, n int) {
+const CellWidth = 20 // default max width of a table cell
+
+// Head
+// first n records, quote-aware.
+// with table, records are split by sep and shown in a table,
+// the header is separated and cells are truncated to width.
+// the total of records is shown under the table if the file has a fresh index
+func Head(path string, header bool, sep string, n int, table bool, width int) {
 	// check file existence
 	if !utility.FileIsExist(path) {
-		fmt.Print("File does not exist. Try command 'gsv frequency --help'.")
+		fmt.Print("File does not exist. Try command 'gsv head --help'.")
 		return
 	}
 
 	r, err := os.Open(path)
-	defer r.Close()
 	utility.CheckErr(err)
+	defer r.Close()
+
+	br := utility.NewRecordScanner(r)
+	if !table {
+		for i := 0; i < n && br.Scan(); i++ {
+			fmt.Println(br.Text())
+		}
+		return
+	}
 
-	br := bufio.NewScanner(r)
-	i := 0
-	for br.Scan() {
-		i++
-		fmt.Println(br.Text())
-		if i >= n {
-			break
+	var names []string
+	if header && br.Scan() {
+		names = strings.Split(br.Text(), sep)
+	}
+	var rows [][]string
+	for i := 0; i < n && br.Scan(); i++ {
+		rows = append(rows, strings.Split(br.Text(), sep))
+	}
+	utility.RenderTable(os.Stdout, names, rows, 0, width)
+	if idx, fresh := utility.LoadIndex(path); fresh {
+		total := idx.Records
+		if header && total > 0 {
+			total--
+		}
+		if len(rows) == 0 {
+			fmt.Printf("No records shown of %d\n", total)
+		} else {
+			fmt.Printf("Records 0-%d of %d\n", len(rows)-1, total)
 		}
 	}
 }
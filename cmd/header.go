@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"strconv"
@@ -11,7 +10,25 @@ import (
 	"github.com/ribbondz/gsv/cmd/utility"
 )
 
-func Header(file string, sep string) {
+const (
+	HeaderRows    = 1000 // default rows read for types, null rates and samples
+	HeaderSamples = 5    // default distinct sample values of a column
+)
+
+// headerColumn
+// type, nulls and distinct sample values of a column in the first rows
+type headerColumn struct {
+	cType   int
+	nulls   int
+	samples []string
+}
+
+// Header
+// index, name, inferred type, null rate and distinct sample values of each column,
+// from the first n rows.
+// ragged rows are fixed by the policy of ragged, missing fields of kept rows are counted as nulls,
+// and columns beyond the header are named by position
+func Header(file string, header bool, sep string, n int, samples int, ragged *utility.Ragged) {
 	// check file existence
 	if !utility.FileIsExist(file) {
 		fmt.Print("File does not exist. Try command 'gsv header --help'.")
@@ -21,27 +38,95 @@ func Header(file string, sep string) {
 	// open file
 	f, _ := os.Open(file)
 	defer f.Close()
-	br := bufio.NewScanner(f)
+	br := utility.NewRecordScanner(f)
 
-	// first and second rows
-	br.Scan()
-	row1 := strings.Split(br.Text(), sep)
-	br.Scan()
-	row2 := strings.Split(br.Text(), sep)
+	// column names
+	var names []string
+	if header {
+		if !br.Scan() {
+			fmt.Print("Empty file.")
+			return
+		}
+		names = strings.Split(br.Text(), sep)
+	}
+
+	// first n rows
+	var columns []headerColumn
+	rows, unequal := 0, 0
+	fix, finish := ragged.Filter(file, header)
+	for rows < n && br.Scan() {
+		row, ok, err := fix(br.Text())
+		if err != nil {
+			finish()
+			fmt.Println(err.Error())
+			return
+		}
+		if !ok {
+			continue
+		}
+		fields := utility.NormalizeFields(strings.Split(row, sep))
+		if header && len(fields) != len(names) || !header && rows > 0 && len(fields) != len(columns) {
+			unequal++
+		}
+		for len(columns) < len(fields) {
+			// nulls in previous rows without the column
+			columns = append(columns, headerColumn{cType: IsNull, nulls: rows})
+		}
+		for i := range columns {
+			c := &columns[i]
+			if i >= len(fields) || utility.IsNullValue(fields[i]) {
+				c.nulls++
+				continue
+			}
+			c.cType = utility.GuessFieldType(c.cType, fields[i])
+			if len(c.samples) < samples && !utility.SliceContainsString(c.samples, fields[i]) {
+				c.samples = append(c.samples, fields[i])
+			}
+		}
+		rows++
+	}
+	finish()
+	if !header && rows == 0 {
+		fmt.Print("Empty file.")
+		return
+	}
+	for len(columns) < len(names) {
+		columns = append(columns, headerColumn{cType: IsNull, nulls: rows})
+	}
 
 	var result [][]string
-	for i := range row1 {
+	for i, c := range columns {
+		name := "col_" + strconv.Itoa(i+1)
+		if i < len(names) {
+			name = names[i]
+		}
+		nullRate := "-"
+		if rows > 0 {
+			nullRate = strconv.FormatFloat(100*float64(c.nulls)/float64(rows), 'f', 2, 64) + "%"
+		}
+		var sample []string
+		for _, v := range c.samples {
+			sample = append(sample, utility.TruncateCell(v, CellWidth))
+		}
 		result = append(result, []string{
 			strconv.Itoa(i),
-			row1[i],
-			row2[i],
+			name,
+			utility.ColTypeName(c.cType),
+			nullRate,
+			strings.Join(sample, ", "),
 		})
 	}
 
+	caption := "Total columns: " + strconv.Itoa(len(result)) + ", from first " + strconv.Itoa(rows) + " rows"
+	if unequal > 0 {
+		caption += ", rows of unequal length: " + strconv.Itoa(unequal)
+	}
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"#", "header", "row example"})
+	table.SetHeader([]string{"#", "header", "type", "null", "samples"})
 	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
 	table.AppendBulk(result)
-	table.SetCaption(true, "Total columns: "+strconv.Itoa(len(result)))
+	table.SetCaption(true, caption)
 	table.Render()
 }
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+const (
+	HistBarWidth   = 50     // width of the longest bar
+	QuantileSample = 100000 // sampled values to estimate quantile bin edges
+)
+
+// batch counts of a histogram
+type histCounts struct {
+	bins    []int // values below first edge, bins, values above last edge
+	nulls   int
+	invalid int // not numeric values
+}
+
+func Histogram(file string, header bool, sep string, colPara string, bins int, edgesPara string, quantile bool, out bool, ragged *utility.Ragged) {
+	var et utility.ElapsedTime
+	et.Start()
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv histogram --help'.")
+		return
+	}
+	if bins < 1 {
+		fmt.Print("Number of bins should be positive. Try command 'gsv histogram --help'.")
+		return
+	}
+
+	// file processing
+	f, _ := os.Open(file)
+	defer f.Close()
+	br := bufio.NewScanner(f)
+
+	// column names and header drop
+	columnN := ColumnN(file, sep)
+	var names []string
+	if header {
+		br.Scan()
+		names = strings.Split(br.Text(), sep)
+	} else {
+		for i := 0; i < columnN; i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+	col, err := utility.ColumnIndex(colPara, names)
+	if err != nil {
+		fmt.Print("Column syntax error. Try command 'gsv histogram --help'.")
+		return
+	}
+
+	// bin edges: explicit edges, quantiles of sampled values, or equal width between min and max
+	edges, err := HistogramEdges(file, header, sep, col, bins, edgesPara, quantile)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	hist := histCounts{bins: make([]int, len(edges)+1)}
+	N := 0 // total number of rows
+	// byte ranges of file are read and processed in parallel, results are merged in order
+	release, err := utility.ParallelRead(file, header, false, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return histProcessRows(rows, sep, col, edges)
+	}, func(result interface{}, n int) {
+		N += n
+		r := result.(histCounts)
+		for i, v := range r.bins {
+			hist.bins[i] += v
+		}
+		hist.nulls += r.nulls
+		hist.invalid += r.invalid
+	})
+	defer release()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	// histogram table, values out of edges are shown only if there are any
+	total, maxCount := 0, 0
+	for _, v := range hist.bins {
+		total += v
+		if v > maxCount {
+			maxCount = v
+		}
+	}
+	var table [][]string
+	for i, v := range hist.bins {
+		if (i == 0 || i == len(hist.bins)-1) && v == 0 {
+			continue
+		}
+		bar := 0
+		if maxCount > 0 {
+			bar = v * HistBarWidth / maxCount
+		}
+		table = append(table, []string{utility.BinLabel(edges, i-1), strconv.Itoa(v), percentString(v, total), strings.Repeat("#", bar)})
+	}
+
+	if out {
+		for i := range table {
+			table[i] = table[i][:3]
+		}
+		table = utility.PrependStringSlice(table, []string{"bin", "count", "percent"})
+		outFile := utility.OutFilename(file, "histogram")
+		utility.SaveFile(outFile, table)
+		fmt.Println("Histogram saved to: ", outFile)
+	} else {
+		t := tablewriter.NewWriter(os.Stdout)
+		t.SetHeader([]string{"bin", "count", "percent", "histogram"})
+		t.SetBorder(true)
+		t.SetAutoWrapText(false)
+		t.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+		t.AppendBulk(table)
+		t.SetCaption(true, "Column: "+names[col]+", total records: "+strconv.Itoa(N))
+		t.Render()
+	}
+	fmt.Printf("Null values: %d, not numeric values: %d\n", hist.nulls, hist.invalid)
+	et.EndAndPrint()
+}
+
+// HistogramEdges
+// explicit edges, quantiles of sampled values,
+// or equal width bins between min and max computed by column statistics
+func HistogramEdges(file string, header bool, sep string, col int, bins int, edgesPara string, quantile bool) ([]float64, error) {
+	if edgesPara != "" {
+		return utility.ParseEdges(edgesPara)
+	}
+	if quantile {
+		values := sampleColumnValues(file, header, sep, col, QuantileSample)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("column %d has no numeric value", col)
+		}
+		return utility.QuantileEdges(values, bins), nil
+	}
+	stat, names, _, err := ColumnStats(file, header, sep)
+	if err != nil {
+		return nil, err
+	}
+	if col >= len(stat) {
+		return nil, fmt.Errorf("column %d out of range", col)
+	}
+	min, max, ok := stat[col].NumericRange()
+	if !ok {
+		return nil, fmt.Errorf("column %s is not numeric", names[col])
+	}
+	return utility.EqualWidthEdges(min, max, bins), nil
+}
+
+// histogram counts of batch rows
+func histProcessRows(rows []string, sep string, col int, edges []float64) (r histCounts) {
+	r.bins = make([]int, len(edges)+1)
+	for _, row := range rows {
+		fields := strings.Split(row, sep)
+		field := ""
+		if col < len(fields) {
+			field = utility.Normalize(fields[col])
+		}
+		if utility.IsNullValue(field) {
+			r.nulls++
+			continue
+		}
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			r.invalid++
+			continue
+		}
+		r.bins[utility.BinIndex(edges, v)+1]++
+	}
+	return
+}
+
+// sampleColumnValues
+// reservoir sample of numeric values in a column, seeded to be reproducible
+func sampleColumnValues(file string, header bool, sep string, col int, size int) (sample []float64) {
+	f, _ := os.Open(file)
+	defer f.Close()
+	br := bufio.NewScanner(f)
+	if header {
+		br.Scan()
+	}
+	rd := rand.New(rand.NewSource(1))
+	seen := 0
+	for br.Scan() {
+		fields := strings.Split(br.Text(), sep)
+		if col >= len(fields) {
+			continue
+		}
+		v, err := strconv.ParseFloat(utility.Normalize(fields[col]), 64)
+		if err != nil {
+			continue
+		}
+		seen++
+		if len(sample) < size {
+			sample = append(sample, v)
+		} else if j := rd.Intn(seen); j < size {
+			sample[j] = v
+		}
+	}
+	return
+}
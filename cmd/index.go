@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+func Index(file string, step int) {
+	var et utility.ElapsedTime
+	et.Start()
+
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv index --help'.")
+		return
+	}
+	if step < 1 {
+		fmt.Print("Records between offsets should be positive. Try command 'gsv index --help'.")
+		return
+	}
+
+	idx, err := utility.BuildIndex(file, step)
+	utility.CheckErr(err)
+	path := utility.IndexFilename(file)
+	err = idx.Save(path)
+	utility.CheckErr(err)
+
+	fmt.Printf("Records: %d, lines: %d, offsets: %d\n", idx.Records, idx.Lines, len(idx.Offsets))
+	fmt.Printf("Index saved to: %s\n", path)
+	et.EndAndPrint()
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+// Lint
+// check file for common problems, each is reported with its count and first line numbers.
+// batches of rows are checked in parallel and merged in file order.
+// the result is false if there is any problem
+func Lint(file string, header bool, sep string) bool {
+	var et utility.ElapsedTime
+	et.Start()
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv lint --help'.")
+		return false
+	}
+
+	// first record, the header, with its line terminator
+	f, _ := os.Open(file)
+	br := utility.NewRecordScanner(f)
+	br.Split(utility.ScanRawRecords)
+	br.Scan()
+	firstRaw := br.Text()
+	f.Close()
+	first, _ := utility.SplitQuoted(strings.TrimPrefix(strings.TrimRight(firstRaw, "\r\n"), "\uFEFF"), sep, nil)
+	fieldN := len(first)
+
+	issues := make(map[string]*utility.LintIssue)
+	for _, c := range append(append([]string{}, utility.LintChecks...), utility.LintEndings...) {
+		issues[c] = &utility.LintIssue{}
+	}
+	thousands := make(map[int]int)
+	duplicates := utility.NewDuplicateRows(utility.LintDuplicateMaxBytes)
+	records, lines := 0, 0
+
+	// header names, and checks of the header record except those of data values.
+	// data lines are numbered after the header
+	var headerDetail []string
+	if header && firstRaw != "" {
+		names := make(map[string]bool)
+		for i, name := range first {
+			name = strings.TrimSpace(utility.Unquote(name))
+			if name == "" {
+				issues[utility.LintHeader].Add(1)
+				headerDetail = append(headerDetail, "empty #"+strconv.Itoa(i))
+			} else if names[name] {
+				issues[utility.LintHeader].Add(1)
+				headerDetail = append(headerDetail, "duplicate "+name)
+			}
+			names[name] = true
+		}
+		b := utility.LintProcessRows([]string{firstRaw}, sep, fieldN)
+		for k, s := range b.Issues {
+			if k != utility.LintThousands {
+				issues[k].Merge(s, 0)
+			}
+		}
+		lines = b.Lines
+	}
+
+	err := utility.ChunkedReadRaw(file, header, BatchRowsPerStat, func(rows []string) interface{} {
+		return utility.LintProcessRows(rows, sep, fieldN)
+	}, func(r interface{}, n int) {
+		b := r.(*utility.LintBatch)
+		for k, s := range b.Issues {
+			issues[k].Merge(s, lines)
+		}
+		for c, k := range b.Thousands {
+			thousands[c] += k
+		}
+		for _, row := range b.Rows {
+			if duplicates.Add(row.Hash, row.Row) {
+				issues[utility.LintDuplicates].Add(lines + row.Line)
+			}
+		}
+		records += n
+		lines += b.Lines
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+
+	details := map[string]string{
+		utility.LintFieldCount:  "expected " + strconv.Itoa(fieldN) + " fields",
+		utility.LintLineEndings: utility.LintMixedEndings(issues),
+		utility.LintHeader:      strings.Join(headerDetail, ", "),
+		utility.LintThousands:   lintThousandColumns(thousands, first, header),
+	}
+	if duplicates.Partial {
+		details[utility.LintDuplicates] = "partial, later rows are compared with the first " +
+			strconv.Itoa(utility.LintDuplicateMaxBytes/1024/1024) + "MB of distinct rows"
+	}
+
+	total := 0
+	var table [][]string
+	for _, c := range utility.LintChecks {
+		s := issues[c]
+		var ls []string
+		for _, l := range s.Lines {
+			ls = append(ls, strconv.Itoa(l))
+		}
+		if s.Count > len(s.Lines) {
+			ls = append(ls, "...")
+		}
+		detail := "-"
+		if (s.Count > 0 || c == utility.LintDuplicates) && details[c] != "" {
+			detail = details[c]
+		}
+		table = append(table, []string{c, strconv.Itoa(s.Count), strings.Join(ls, ", "), detail})
+		total += s.Count
+	}
+	t := tablewriter.NewWriter(os.Stdout)
+	t.SetHeader([]string{"check", "count", "first lines", "detail"})
+	t.SetAutoWrapText(false)
+	t.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	t.AppendBulk(table)
+	t.SetCaption(true, "Records: "+strconv.Itoa(records)+", lines: "+strconv.Itoa(lines))
+	t.Render()
+	et.EndAndPrint()
+	return total == 0
+}
+
+// lintThousandColumns
+// columns with thousand separators and their number of values
+func lintThousandColumns(thousands map[int]int, names []string, header bool) string {
+	var cols []int
+	for c := range thousands {
+		cols = append(cols, c)
+	}
+	sort.Ints(cols)
+	var r []string
+	for _, c := range cols {
+		name := "col_" + strconv.Itoa(c+1)
+		if header && c < len(names) {
+			name = utility.Unquote(names[c])
+		}
+		r = append(r, name+" "+strconv.Itoa(thousands[c]))
+	}
+	return strings.Join(r, ", ")
+}
@@ -3,13 +3,17 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"container/list"
+	"encoding/csv"
 	"fmt"
 	"hash/fnv"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ribbondz/gsv/cmd/utility"
@@ -17,21 +21,46 @@ import (
 )
 
 var (
-	BarUpdateThreshold = 1024 * 1024 * 20   // 20MB
-	Batch              = 1024 * 1024 * 2000 // 2000MB
+	BarUpdateThreshold = 1024 * 1024 * 20 // 20MB
+	PartWriterBuffer   = 1024 * 64        // 64KB buffer for each open partition file
+)
+
+// naming of partition files
+const (
+	NamingHash      = "hash"       // fnv hash of column values
+	NamingValue     = "value"      // sanitized column values
+	NamingValueHash = "value+hash" // sanitized column values followed by a short hash
+	ManifestFile    = "_manifest.csv"
 )
 
 type BufHandler struct {
-	summary     map[string]int // summary
+	summary     map[string]int // summary, key is values of partition columns joined by separator
 	dstDir      string
 	sep         []byte
-	column      int
+	keys        []utility.KeyExpr // partition keys, columns or expressions on columns
+	names       []string          // names of partition keys, used in hive-style directories
+	hive        bool              // nested directories as name=value/name=value/part.txt
+	naming      string            // naming of flat partition files
+	files       map[string]string // partition file relative to dstDir, key is values of partition columns
+	used        map[string]bool   // lower-cased file names in use, for case-insensitive file systems
+	ext         string            // extension of partition files
 	headerBytes []byte
 	header      bool
-	lineN       int
+	recordN     int
+	batch       int              // bytes of rows cached before writing
+	writers     []chan partWrite // concurrent writers, a file is always written by the same writer
+	pending     sync.WaitGroup   // writes of current batch
+	closed      sync.WaitGroup   // writers finished and files closed
+	progress    chan int         // bytes written, for progress bar
+}
+
+// content to append to a partition file
+type partWrite struct {
+	path    string
+	content []byte
 }
 
-func Partition(file string, header bool, column int, sep string, summary bool) {
+func Partition(file string, header bool, colPara string, keyPara string, sep string, summary bool, hive bool, naming string, memory int, maxOpenFiles int, ragged *utility.Ragged) {
 	var et utility.ElapsedTime
 	et.Start()
 
@@ -40,9 +69,46 @@ func Partition(file string, header bool, column int, sep string, summary bool) {
 		fmt.Print("File does not exist. Try command 'gsv partition --help'.")
 		return
 	}
+	if naming != NamingHash && naming != NamingValue && naming != NamingValueHash {
+		fmt.Print("Naming should be one of value, hash and value+hash. Try command 'gsv partition --help'.")
+		return
+	}
+	if memory < 1 || maxOpenFiles < 1 {
+		fmt.Print("Memory and max open files should be positive. Try command 'gsv partition --help'.")
+		return
+	}
 	r, _ := os.Open(file)
 	defer r.Close()
-	br := bufio.NewScanner(r)
+	br := utility.NewRecordScanner(r)
+
+	// partition columns by name or index, or key expressions
+	var names []string
+	if header {
+		names = strings.Split(string(utility.HeaderBytes(file)), sep)
+	} else {
+		for i := 0; i < ColumnN(file, sep); i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+	var keys []utility.KeyExpr
+	if keyPara != "" {
+		var err error
+		keys, err = utility.ParseKeyExpr(keyPara, names)
+		if err != nil {
+			fmt.Println(err.Error())
+			fmt.Print("Partition key syntax error. Try command 'gsv partition --help'.")
+			return
+		}
+	} else {
+		columns, err := utility.ParseColNames(colPara, names)
+		if err != nil {
+			fmt.Print("Partition column syntax error. Try command 'gsv partition --help'.")
+			return
+		}
+		for _, c := range columns {
+			keys = append(keys, utility.KeyExpr{Func: utility.KeyColumn, Col: c, Name: names[c]})
+		}
+	}
 
 	// estimate number of rows
 	estimatedTotalN := utility.EstimateRowNumber(file, header, 20) //20MB
@@ -53,25 +119,56 @@ func Partition(file string, header bool, column int, sep string, summary bool) {
 	handler.summary = make(map[string]int)
 	handler.dstDir = dstDirectory(file) // mkdir and return the path
 	handler.sep = []byte(sep)
-	handler.column = column
+	handler.keys = keys
 	handler.header = header
+	handler.hive = hive
+	handler.naming = naming
+	handler.files = make(map[string]string)
+	handler.used = make(map[string]bool)
+	handler.used[strings.ToLower(strings.TrimSuffix(ManifestFile, filepath.Ext(ManifestFile)))] = true // reserved for the manifest
+	handler.ext = filepath.Ext(file)
+	if handler.ext == "" {
+		handler.ext = ".txt"
+	}
+	// one batch is cached while the previous one is being written
+	handler.batch = memory * 1024 * 1024 / 2
 
 	if header && br.Scan() {
 		// must copy because s.token change under the hood
 		handler.headerBytes = utility.CopyBytes(br.Bytes())
 	}
+	for _, k := range keys {
+		handler.names = append(handler.names, k.Name)
+	}
 
 	// progress bar
 	size := utility.FileSize(file)
 	bar := progressbar.NewOptions(size,
 		progressbar.OptionSetBytes(size),
 		progressbar.OptionSetRenderBlankState(true))
+	handler.progress = make(chan int, 100)
+	barDone := make(chan int)
+	go func() {
+		t := 0
+		for b := range handler.progress {
+			t += b
+			if t > BarUpdateThreshold { // update every 20MB
+				bar.Add(t)
+				t = 0
+			}
+		}
+		bar.Add(t)
+		barDone <- 1
+	}()
+	handler.startWriters(maxOpenFiles)
+
 	// task
 	type task struct {
 		m     map[string][]byte // cached content
 		byteN int               // number of processed bytes
 	}
 	jobs := make(chan task)
+	fix, finish := ragged.Filter(file, header)
 	// goroutine reading
 	go func() {
 		var (
@@ -81,9 +178,9 @@ func Partition(file string, header bool, column int, sep string, summary bool) {
 		)
 
 		for br.Scan() {
-			handler.lineN++
+			handler.recordN++
 			// submit to write if currentN is no less than conf.batch
-			if byteN > Batch {
+			if byteN > handler.batch {
 				jobs <- task{m, byteN}
 				byteN = 0
 				m = make(map[string][]byte)
@@ -91,9 +188,17 @@ func Partition(file string, header bool, column int, sep string, summary bool) {
 			// continue reading
 			line = br.Bytes()
 			byteN += len(line) + 2 // 2 is for line terminator
-			fields := bytes.Split(line, handler.sep)
-			if len(fields) > handler.column {
-				f := string(fields[handler.column])
+			row, ok, err := fix(string(line))
+			if err != nil {
+				break
+			}
+			if !ok {
+				continue
+			}
+			if len(row) != len(line) {
+				line = []byte(row) // fixed by the ragged policy
+			}
+			if f, ok := handler.key(line); ok {
 				a := append(m[f], line...)
 				a = append(a, '\n')
 				m[f] = a
@@ -114,46 +219,43 @@ func Partition(file string, header bool, column int, sep string, summary bool) {
 
 	// write
 	for t := range jobs {
-		handler.SaveContent(t.m, bar)
+		handler.SaveContent(t.m)
 	}
+	handler.closeWriters()
+	close(handler.progress)
+	<-barDone
 	bar.Finish()
+	if err := finish(); err != nil {
+		fmt.Println("\n" + err.Error())
+		return
+	}
 
 	// print summary info
-	fmt.Printf("\n\nLine count: %d, unique column value: %d\n", handler.lineN, len(handler.summary))
+	fmt.Printf("\n\nRecord count: %d, unique column value: %d\n", handler.recordN, len(handler.summary))
+
+	// manifest in destination directory, always written
+	manifestFile := filepath.Join(handler.dstDir, ManifestFile)
+	WriteManifest(manifestFile, handler.summary, handler.files, handler.names, sep)
 
 	// summary
 	if summary {
 		summaryFile := summaryFilename(file)
-		WriteSummary(summaryFile, handler.summary)
+		WriteSummary(summaryFile, handler.summary, handler.names, sep)
 	}
 
 	et.EndAndPrint()
 }
 
-func (handler *BufHandler) SaveContent(content map[string][]byte, bar *progressbar.ProgressBar) {
-	result := make(chan int, 100) // bytes saved
-	done := make(chan int)        // wait progress bar update
-
-	go func() {
-		t := 0
-		for b := range result {
-			t += b
-			if t > BarUpdateThreshold { // update every 20MB
-				bar.Add(t)
-				t = 0
-			}
-		}
-		bar.Add(t)
-		done <- 1
-	}()
-
+// SaveContent
+// header and partition file are decided here, contents are then written by concurrent writers.
+// it returns when the batch is written, so that at most two batches are held in memory.
+func (handler *BufHandler) SaveContent(content map[string][]byte) {
 	for k, v := range content {
-		result <- len(v)
-
 		// append a header for first time write
+		_, written := handler.summary[k]
+		handler.summary[k] += bytes.Count(v, []byte{'\n'}) // data lines, header excluded
 		if handler.header {
-			_, ok := handler.summary[k]
-			if !ok {
+			if !written {
 				lh := len(handler.headerBytes) // copy is more efficient than append
 				t := make([]byte, lh+1+len(v))
 				copy(t, handler.headerBytes)
@@ -163,12 +265,212 @@ func (handler *BufHandler) SaveContent(content map[string][]byte, bar *progressb
 			}
 		}
 
-		handler.summary[k] += bytes.Count(v, []byte{'\n'})
-		AppendToFile(handler.dstDir, k, v)
+		path := handler.partitionFile(k)
+		handler.pending.Add(1)
+		handler.writers[shortHashIndex(path, len(handler.writers))] <- partWrite{path, v}
+	}
+	handler.pending.Wait()
+}
+
+// startWriters
+// one goroutine per cpu, each holding an lru cache of open files,
+// max open files are shared by writers
+func (handler *BufHandler) startWriters(maxOpenFiles int) {
+	n := runtime.NumCPU()
+	if n > maxOpenFiles {
+		n = maxOpenFiles
+	}
+	handler.writers = make([]chan partWrite, n)
+	for i := range handler.writers {
+		ch := make(chan partWrite, 100)
+		handler.writers[i] = ch
+		cache := newWriterCache(maxOpenFiles / n)
+		handler.closed.Add(1)
+		go func() {
+			for w := range ch {
+				cache.write(w.path, w.content)
+				handler.progress <- len(w.content)
+				handler.pending.Done()
+			}
+			cache.closeAll()
+			handler.closed.Done()
+		}()
 	}
+}
 
-	close(result)
-	<-done
+// closeWriters
+// flush and close all open files
+func (handler *BufHandler) closeWriters() {
+	for _, ch := range handler.writers {
+		close(ch)
+	}
+	handler.closed.Wait()
+}
+
+// buffered writer of an open partition file
+type partWriter struct {
+	path string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// writerCache
+// least recently used open partition files,
+// the least recently used one is flushed and closed when cache is full
+type writerCache struct {
+	capacity int
+	order    *list.List               // front is the most recently used
+	items    map[string]*list.Element // key is file path
+}
+
+func newWriterCache(capacity int) *writerCache {
+	return &writerCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// write
+// content is appended, so a closed file can be opened again for later batches
+func (c *writerCache) write(path string, content []byte) {
+	var pw *partWriter
+	if e, ok := c.items[path]; ok {
+		c.order.MoveToFront(e)
+		pw = e.Value.(*partWriter)
+	} else {
+		if c.order.Len() >= c.capacity {
+			c.close(c.order.Back())
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		utility.CheckErr(err)
+		pw = &partWriter{path, f, bufio.NewWriterSize(f, PartWriterBuffer)}
+		c.items[path] = c.order.PushFront(pw)
+	}
+	_, err := pw.w.Write(content)
+	utility.CheckErr(err)
+}
+
+func (c *writerCache) close(e *list.Element) {
+	pw := c.order.Remove(e).(*partWriter)
+	delete(c.items, pw.path)
+	utility.CheckErr(pw.w.Flush())
+	utility.CheckErr(pw.f.Close())
+}
+
+func (c *writerCache) closeAll() {
+	for c.order.Len() > 0 {
+		c.close(c.order.Back())
+	}
+}
+
+// key
+// values of partition keys joined by separator, expressions are evaluated on each row,
+// rows without all partition columns are skipped
+func (handler *BufHandler) key(line []byte) (string, bool) {
+	fields := bytes.Split(line, handler.sep)
+	if len(handler.keys) == 1 && handler.keys[0].Func == utility.KeyColumn {
+		if handler.keys[0].Col >= len(fields) {
+			return "", false
+		}
+		return string(fields[handler.keys[0].Col]), true
+	}
+	values := make([][]byte, len(handler.keys))
+	for i, k := range handler.keys {
+		if k.Col >= len(fields) {
+			return "", false
+		}
+		if k.Func == utility.KeyColumn {
+			values[i] = fields[k.Col]
+		} else {
+			values[i] = []byte(k.Eval(string(fields[k.Col])))
+		}
+	}
+	return string(bytes.Join(values, handler.sep)), true
+}
+
+// partitionFile
+// flat file named by key, or hive-style nested directories as name=value/part.txt.
+// the file of a key is decided at first write and kept for later batches.
+func (handler *BufHandler) partitionFile(key string) string {
+	if f, ok := handler.files[key]; ok {
+		return filepath.Join(handler.dstDir, f)
+	}
+
+	var f string
+	if handler.hive {
+		dir := ""
+		for i, v := range strings.Split(key, string(handler.sep)) {
+			dir = filepath.Join(dir, hiveDirName(handler.names[i], v))
+		}
+		err := os.MkdirAll(filepath.Join(handler.dstDir, dir), os.ModePerm)
+		utility.CheckErr(err)
+		f = filepath.Join(dir, "part"+handler.ext)
+	} else {
+		f = handler.flatFileName(key)
+	}
+	handler.files[key] = f
+	return filepath.Join(handler.dstDir, f)
+}
+
+// flatFileName
+// file name by naming option with the source extension, values of multiple columns are joined by '_'.
+// a short hash is appended when sanitized values collide, e.g., a/b and a_b, or A and a.
+func (handler *BufHandler) flatFileName(key string) string {
+	if handler.naming == NamingHash {
+		return HashedFileName(key, handler.ext)
+	}
+
+	values := strings.Split(key, string(handler.sep))
+	for i, v := range values {
+		values[i] = utility.SanitizeFilename(v)
+	}
+	name := strings.Join(values, "_")
+	if handler.naming == NamingValueHash || handler.used[strings.ToLower(name)] {
+		name += "-" + shortHash(key)
+	}
+	// extremely rare, collision of hash
+	base := name
+	for i := 2; handler.used[strings.ToLower(name)]; i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	handler.used[strings.ToLower(name)] = true
+	return name + handler.ext
+}
+
+// 8 hex digits of fnv hash
+func shortHash(s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// index in [0, n) by fnv hash
+func shortHashIndex(s string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return int(h.Sum32() % uint32(n))
+}
+
+// hive-style directory name, '%', path separators and '=' in value are escaped,
+// empty value is named as in hive.
+// values are sanitized and truncated as flat file names, and those changed by it
+// get a short hash of the original value, so that distinct values keep distinct directories.
+// values differing only in case, e.g., A and a, are kept as they are,
+// and share a directory on case-insensitive file systems
+func hiveDirName(name string, value string) string {
+	if value == "" {
+		value = "__HIVE_DEFAULT_PARTITION__"
+	}
+	escaped := strings.NewReplacer("%", "%25", "/", "%2F", "\\", "%5C", "=", "%3D").Replace(value)
+	if escaped == "." || escaped == ".." {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	v := utility.SanitizeFilename(escaped)
+	if v != escaped {
+		v += "-" + shortHash(value)
+	}
+	return utility.SanitizeFilename(name) + "=" + v
 }
 
 func dstDirectory(file string) string {
@@ -190,39 +492,53 @@ func summaryFilename(file string) string {
 	return filepath.Join(wd, file+"-split-summary-"+timeStr+".txt")
 }
 
-func AppendToFile(dir string, col string, content []byte) {
-	name := HashedFileName(col)
-	file := filepath.Join(dir, name)
-	f, _ := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	bf := bufio.NewWriter(f)
-	bf.Write(content)
-	bf.Flush()
-	f.Close()
-}
-
-func HashedFileName(name string) (filename string) {
+func HashedFileName(name string, ext string) (filename string) {
 	h := fnv.New64a()
 	h.Write([]byte(name))
-	filename = strconv.FormatUint(h.Sum64(), 10) + ".txt"
+	filename = strconv.FormatUint(h.Sum64(), 10) + ext
 	return
 }
 
-func WriteSummary(path string, summary map[string]int) {
+// WriteManifest
+// partition columns values, partition file relative to destination directory and line counts
+func WriteManifest(path string, summary map[string]int, files map[string]string, names []string, sep string) {
+	var result [][]string
+	for k, v := range summary {
+		row := strings.SplitN(k, sep, len(names))
+		result = append(result, append(row, filepath.ToSlash(files[k]), strconv.Itoa(v)))
+	}
+	// sort by file name
+	n := len(names)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][n] < result[j][n]
+	})
+	// add header
+	result = utility.PrependStringSlice(result, append(append([]string{}, names...), "file", "count"))
+	// save, replacing any file of the same name
+	f, err := os.Create(path)
+	utility.CheckErr(err)
+	utility.CheckErr(csv.NewWriter(f).WriteAll(result))
+	utility.CheckErr(f.Close())
+	fmt.Printf("Manifest file saved to: %s\n", path)
+}
+
+// WriteSummary
+// line counts for each key combination of partition columns
+func WriteSummary(path string, summary map[string]int, names []string, sep string) {
 	var result [][]string
 	for k, v := range summary {
-		result = append(result, []string{
-			k,
-			strconv.Itoa(v),
-		})
+		row := strings.SplitN(k, sep, len(names))
+		result = append(result, append(row, strconv.Itoa(v)))
 	}
-	// sort by col
+	// sort by count
+	n := len(names)
 	sort.Slice(result, func(i, j int) bool {
-		a, _ := strconv.ParseInt(result[i][1], 10, 64)
-		b, _ := strconv.ParseInt(result[j][1], 10, 64)
+		a, _ := strconv.ParseInt(result[i][n], 10, 64)
+		b, _ := strconv.ParseInt(result[j][n], 10, 64)
 		return a > b
 	})
 	// add header
-	result = utility.PrependStringSlice(result, []string{"col", "count"})
+	result = utility.PrependStringSlice(result, append(append([]string{}, names...), "count"))
 	// save
 	utility.SaveFile(path, result)
 	fmt.Printf("Summary file saved to: %s\n", path)
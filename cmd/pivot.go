@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+const (
+	PercentNone = ""
+	PercentRow  = "row"
+	PercentCol  = "col"
+	PercentAll  = "all"
+	PivotTotal  = "(total)"
+)
+
+func Pivot(file string, header bool, sep string, rowPara string, colPara string, valPara string, aggPara string, percent string, out bool, maxCols int, ragged *utility.Ragged) {
+	var et utility.ElapsedTime
+	et.Start()
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv pivot --help'.")
+		return
+	}
+	if percent != PercentNone && percent != PercentRow && percent != PercentCol && percent != PercentAll {
+		fmt.Print("Percent option should be row, col or all. Try command 'gsv pivot --help'.")
+		return
+	}
+
+	// file processing
+	f, _ := os.Open(file)
+	defer f.Close()
+	br := bufio.NewScanner(f)
+
+	// column names and header drop
+	columnN := ColumnN(file, sep)
+	var names []string
+	if header {
+		br.Scan()
+		names = strings.Split(br.Text(), sep)
+	} else {
+		for i := 0; i < columnN; i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+
+	// row key, column key, and aggregation on value column.
+	// count is a plain crosstab of row key by column key.
+	rowCol, err1 := utility.ColumnIndex(rowPara, names)
+	colCol, err2 := utility.ColumnIndex(colPara, names)
+	if err1 != nil || err2 != nil {
+		fmt.Print("Row or column key syntax error. Try command 'gsv pivot --help'.")
+		return
+	}
+	keys := []int{rowCol, colCol}
+	isCount := strings.ToLower(aggPara) == "count"
+	var aggs []utility.Agg
+	if !isCount {
+		if percent != PercentNone {
+			fmt.Print("Percent option is only available in count mode. Try command 'gsv pivot --help'.")
+			return
+		}
+		var err error
+		aggs, err = utility.ParseAggArg(aggPara+"("+valPara+")", names)
+		if err != nil {
+			fmt.Printf("Aggregation syntax error: %s. Try command 'gsv pivot --help'.\n", err.Error())
+			return
+		}
+	}
+
+	freq := []map[string]int{make(map[string]int)} // crosstab count
+	groups := make(map[string]*GroupAgg)
+	N := 0 // total number of rows
+	// byte ranges of file are read and processed in parallel, results are merged in order
+	release, err := utility.ParallelRead(file, header, false, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		if isCount {
+			return crosstabProcessRows(rows, sep, keys)
+		}
+		return aggProcessRows(rows, sep, keys, aggs)
+	}, func(result interface{}, n int) {
+		N += n
+		if isCount {
+			freq = MergeMapList(freq, result.([]map[string]int))
+		} else {
+			groups = mergeGroupAgg(groups, result.(map[string]*GroupAgg))
+		}
+	})
+	defer release()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	// pivot to a wide table
+	var cells map[string]map[string]string
+	var rowKeys, colKeys []string
+	if isCount {
+		cells, rowKeys, colKeys = crosstabCells(freq[0], sep, percent)
+	} else {
+		cells, rowKeys, colKeys = pivotCells(groups, sep, aggs[0])
+	}
+	if maxCols > 0 && len(colKeys) > maxCols {
+		fmt.Printf("Too many column keys: %d unique values in column %s. Try a larger --max-cols.\n", len(colKeys), names[colCol])
+		return
+	}
+
+	tableHeader := append([]string{names[rowCol] + " \\ " + names[colCol]}, colKeys...)
+	tableHeader = append(tableHeader, PivotTotal)
+	var table [][]string
+	for _, r := range append(rowKeys, PivotTotal) {
+		row := []string{r}
+		for _, c := range append(colKeys, PivotTotal) {
+			row = append(row, cells[r][c])
+		}
+		table = append(table, row)
+	}
+
+	if out {
+		table = utility.PrependStringSlice(table, tableHeader)
+		outFile := utility.OutFilename(file, "pivot")
+		utility.SaveFile(outFile, table)
+		fmt.Println("Pivot table saved to: ", outFile)
+	} else {
+		t := tablewriter.NewWriter(os.Stdout)
+		t.SetHeader(tableHeader)
+		t.SetBorder(true)
+		t.SetAutoFormatHeaders(false)
+		t.AppendBulk(table[:len(table)-1])
+		t.SetFooter(table[len(table)-1])
+		t.SetAlignment(tablewriter.ALIGN_RIGHT)
+		t.SetCaption(true, "Total records: "+strconv.Itoa(N))
+		t.Render()
+	}
+	et.EndAndPrint()
+}
+
+// crosstab count of batch rows,
+// the key is row key and column key joined by separator
+func crosstabProcessRows(rows []string, sep string, keys []int) []map[string]int {
+	r := map[string]int{}
+	for _, row := range rows {
+		r[groupKey(utility.NormalizeFields(strings.Split(row, sep)), keys, sep)]++
+	}
+	return []map[string]int{r}
+}
+
+// crosstabCells
+// cells of crosstab count with row and column totals,
+// counts are optionally shown as percentages of row, column or grand total
+func crosstabCells(freq map[string]int, sep string, percent string) (cells map[string]map[string]string, rowKeys, colKeys []string) {
+	counts := make(map[string]map[string]int)
+	add := func(r, c string, v int) {
+		if counts[r] == nil {
+			counts[r] = make(map[string]int)
+		}
+		counts[r][c] += v
+	}
+	for k, v := range freq {
+		rc := strings.SplitN(k, sep, 2)
+		add(rc[0], rc[1], v)
+		add(rc[0], PivotTotal, v)
+		add(PivotTotal, rc[1], v)
+		add(PivotTotal, PivotTotal, v)
+	}
+	var keys []string
+	for k := range freq {
+		keys = append(keys, k)
+	}
+	rowKeys, colKeys = pivotKeys(keys, sep)
+
+	cells = make(map[string]map[string]string)
+	for r, m := range counts {
+		cells[r] = make(map[string]string)
+		for _, c := range append(colKeys, PivotTotal) {
+			var base int
+			switch percent {
+			case PercentRow:
+				base = counts[r][PivotTotal]
+			case PercentCol:
+				base = counts[PivotTotal][c]
+			case PercentAll:
+				base = counts[PivotTotal][PivotTotal]
+			}
+			if base == 0 {
+				cells[r][c] = strconv.Itoa(m[c])
+			} else {
+				cells[r][c] = strconv.FormatFloat(float64(m[c])*100/float64(base), 'f', 2, 64) + "%"
+			}
+		}
+	}
+	return
+}
+
+// pivotCells
+// cells of aggregates with row and column totals,
+// totals are partial aggregates merged along the row or column
+func pivotCells(groups map[string]*GroupAgg, sep string, agg utility.Agg) (cells map[string]map[string]string, rowKeys, colKeys []string) {
+	aggs := []utility.Agg{agg}
+	merged := make(map[string]map[string]*GroupAgg)
+	add := func(r, c string, g *GroupAgg) {
+		if merged[r] == nil {
+			merged[r] = make(map[string]*GroupAgg)
+		}
+		a, ok := merged[r][c]
+		if !ok {
+			a = newGroupAgg("", aggs)
+			merged[r][c] = a
+		}
+		a.Count += g.Count
+		a.Aggs[0].merge(&g.Aggs[0])
+	}
+	for k, g := range groups {
+		rc := strings.SplitN(k, sep, 2)
+		add(rc[0], rc[1], g)
+		add(rc[0], PivotTotal, g)
+		add(PivotTotal, rc[1], g)
+		add(PivotTotal, PivotTotal, g)
+	}
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	rowKeys, colKeys = pivotKeys(keys, sep)
+
+	cells = make(map[string]map[string]string)
+	for r, m := range merged {
+		cells[r] = make(map[string]string)
+		for c, g := range m {
+			cells[r][c] = g.Aggs[0].value(agg, g.Count)
+		}
+	}
+	return
+}
+
+// sorted unique row keys and column keys
+func pivotKeys(keys []string, sep string) (rowKeys, colKeys []string) {
+	rows, cols := make(map[string]bool), make(map[string]bool)
+	for _, k := range keys {
+		rc := strings.SplitN(k, sep, 2)
+		rows[rc[0]] = true
+		cols[rc[1]] = true
+	}
+	for k := range rows {
+		rowKeys = append(rowKeys, k)
+	}
+	for k := range cols {
+		colKeys = append(colKeys, k)
+	}
+	sort.Strings(rowKeys)
+	sort.Strings(colKeys)
+	return
+}
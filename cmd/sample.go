@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+// Sample
+// n records chosen uniformly at random, shown in file order, quote-aware.
+// with a fresh index, record numbers are drawn from the indexed total and read by seeking near each of them;
+// otherwise the file is read once by reservoir sampling.
+// the same seed gives the same sample, with or without an index respectively
+func Sample(file string, header bool, n int, seed int64) {
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv sample --help'.")
+		return
+	}
+	if n < 0 {
+		fmt.Print("Number of records should be non-negative. Try command 'gsv sample --help'.")
+		return
+	}
+
+	f, err := os.Open(file)
+	utility.CheckErr(err)
+	defer f.Close()
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	first := 0 // first data record in file, header included
+	if header {
+		writeRecord(w, headerRecord(f))
+		first = 1
+	}
+	r := rand.New(rand.NewSource(seed))
+	if idx, fresh := utility.LoadIndex(file); fresh {
+		sampleByIndex(w, f, idx, utility.SampleRecords(r, idx.Records-first, n), first)
+	} else {
+		sampleByReservoir(w, f, r, n, first)
+	}
+}
+
+// sampleByIndex
+// records are data record numbers in ascending order, first is the number of records before data.
+// the scanner goes on reading, unless seeking to the indexed offset of the next record skips records
+func sampleByIndex(w *bufio.Writer, f *os.File, idx *utility.Index, records []int, first int) {
+	var br *bufio.Scanner
+	current := -1 // record number in file of the next scanned record, -1 if not positioned
+	for _, k := range records {
+		record := k + first
+		offset, skip := idx.Seek(record)
+		if current < 0 || record < current || skip < record-current {
+			_, err := f.Seek(offset, io.SeekStart)
+			utility.CheckErr(err)
+			br = utility.NewRecordScanner(f)
+			current = record - skip
+		}
+		for ; current <= record; current++ {
+			if !br.Scan() {
+				return
+			}
+		}
+		writeRecord(w, br.Bytes())
+	}
+}
+
+// sampleByReservoir
+// reservoir sampling over data records, the kept records are sorted by record number
+func sampleByReservoir(w *bufio.Writer, f *os.File, r *rand.Rand, n int, first int) {
+	type sampled struct {
+		i      int
+		record []byte
+	}
+	var reservoir []sampled
+	_, err := f.Seek(0, io.SeekStart)
+	utility.CheckErr(err)
+	br := utility.NewRecordScanner(f)
+	if first > 0 {
+		br.Scan()
+	}
+	for i := 0; br.Scan(); i++ {
+		if i < n {
+			reservoir = append(reservoir, sampled{i, utility.CopyBytes(br.Bytes())})
+		} else if j := r.Intn(i + 1); j < n {
+			reservoir[j] = sampled{i, utility.CopyBytes(br.Bytes())}
+		}
+	}
+	sort.Slice(reservoir, func(a, b int) bool {
+		return reservoir[a].i < reservoir[b].i
+	})
+	for _, s := range reservoir {
+		writeRecord(w, s.record)
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+// SchemaInfer
+// schema of file in json, printed to stdout
+func SchemaInfer(file string, header bool, sep string, ragged *utility.Ragged) {
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv schema infer --help'.")
+		return
+	}
+	s, err := InferSchema(file, header, sep, ragged)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// InferSchema
+// names, types, nullability, min and max, enum candidates and patterns of columns,
+// profiles of batches of rows are computed in parallel and merged in order.
+// fields beyond the header are ignored, missing fields are nulls
+func InferSchema(file string, header bool, sep string, ragged *utility.Ragged) (*utility.Schema, error) {
+	columnN := ColumnN(file, sep)
+	var names []string
+	if header {
+		names = strings.Split(string(utility.HeaderBytes(file)), sep)
+	} else {
+		for i := 0; i < columnN; i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+
+	profiles := utility.NewSchemaProfiles(columnN)
+	release, err := utility.ParallelRead(file, header, false, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return utility.SchemaProcessRows(rows, sep, columnN)
+	}, func(r interface{}, n int) {
+		for i, p := range r.([]utility.SchemaProfile) {
+			profiles[i].Merge(p)
+		}
+	})
+	release()
+	if err != nil {
+		return nil, err
+	}
+	return utility.NewSchema(names, header, sep, profiles), nil
+}
+
+// Validate
+// check file against schema, rows are checked in parallel and violations are reported in file order.
+// the result is false if there is any violation
+func Validate(file string, schemaFile string) bool {
+	var et utility.ElapsedTime
+	et.Start()
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv validate --help'.")
+		return false
+	}
+	s, err := utility.LoadSchema(schemaFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	checker, err := utility.NewSchemaChecker(s)
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+
+	counts := make(map[string]int)
+	records := 0
+	report := func(v utility.Violation) {
+		counts[v.Rule]++
+		if v.Col < 0 {
+			fmt.Printf("row %d: %s, %s\n", v.Row, v.Rule, v.Detail)
+			return
+		}
+		name := "col_" + strconv.Itoa(v.Col+1)
+		if v.Col < len(s.Columns) {
+			name = s.Columns[v.Col].Name
+		}
+		fmt.Printf("row %d, column %d (%s): %s, value %q, %s\n", v.Row, v.Col, name, v.Rule, v.Value, v.Detail)
+	}
+
+	// header names
+	base := 0 // records before a batch
+	if s.Header {
+		for _, v := range checker.CheckHeader(strings.Split(string(utility.HeaderBytes(file)), s.Sep)) {
+			report(v)
+		}
+		base = 1
+	}
+
+	release, err := utility.ParallelRead(file, s.Header, false, BatchRowsPerStat, nil, func(rows []string) interface{} {
+		var r []utility.Violation
+		var fields []string // reused across rows
+		for i, row := range rows {
+			fields = utility.SplitInto(row, s.Sep, fields)
+			r = append(r, checker.Check(fields, i)...)
+		}
+		return r
+	}, func(r interface{}, n int) {
+		for _, v := range r.([]utility.Violation) {
+			v.Row += base + 1
+			report(v)
+		}
+		base += n
+		records += n
+	})
+	release()
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+
+	total := 0
+	var table [][]string
+	for _, rule := range utility.SchemaRules {
+		if counts[rule] > 0 {
+			table = append(table, []string{rule, strconv.Itoa(counts[rule])})
+			total += counts[rule]
+		}
+	}
+	if total == 0 {
+		fmt.Printf("Valid, records: %d\n", records)
+	} else {
+		t := tablewriter.NewWriter(os.Stdout)
+		t.SetHeader([]string{"rule", "violations"})
+		t.AppendBulk(table)
+		t.SetFooter([]string{"total", strconv.Itoa(total)})
+		t.Render()
+	}
+	et.EndAndPrint()
+	return total == 0
+}
@@ -6,13 +6,11 @@ import (
 	"github.com/ribbondz/gsv/cmd/utility"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"sync"
 	"time"
 )
 
-func Select(file string, header bool, sep string, filterPara string, colPara utility.ColArgs, out bool) {
+func Select(file string, header bool, sep string, filterPara string, colPara utility.ColArgs, out bool, mmap bool, ragged *utility.Ragged) {
 	var et utility.ElapsedTime
 	et.Start()
 
@@ -33,11 +31,6 @@ func Select(file string, header bool, sep string, filterPara string, colPara uti
 		return
 	}
 
-	// file processing
-	f, _ := os.Open(file)
-	defer f.Close()
-	br := bufio.NewScanner(f)
-
 	// writer
 	dstFilename := OutFilenameFilter(file)
 	r, err := os.OpenFile(dstFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
@@ -45,8 +38,7 @@ func Select(file string, header bool, sep string, filterPara string, colPara uti
 
 	// header with saved column
 	if header {
-		br.Scan()
-		s := keepSavedCol(strings.Split(br.Text(), sep), col, columnN)
+		s := keepSavedCol(strings.Split(string(utility.HeaderBytes(file)), sep), col, columnN)
 		ss := strings.Join(s, sep)
 		if out {
 			bw.WriteString(ss)
@@ -56,62 +48,30 @@ func Select(file string, header bool, sep string, filterPara string, colPara uti
 		}
 	}
 
-	jobs := make(chan []string, 20)      // batch rows
-	results := make(chan [][]string, 20) // batch results, filtered out rows joined in a string
-	wg := &sync.WaitGroup{}              // wait all batch processing
-	total := 0                           // filter out rows count
-
-	// worker, process batch rows
-	// the number of worker defaults to cpu cores
-	for i := 0; i < runtime.NumCPU(); i++ {
-		go func() {
-			for job := range jobs {
-				results <- FilterProcessRows(filter, job, sep, col, columnN)
+	// byte ranges of file are read and filtered in parallel, results are written in file order
+	total := 0 // filter out rows count
+	release, err := utility.ParallelRead(file, header, mmap, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return FilterProcessRows(filter, rows, sep, col, columnN)
+	}, func(r interface{}, n int) {
+		result := r.([][]string)
+		if len(result) > 0 {
+			total += len(result) // filtered out number of rows in the batch
+			var sb strings.Builder
+			for _, s := range result {
+				sb.WriteString(strings.Join(s, sep))
+				sb.WriteByte('\n')
 			}
-		}()
-	}
-
-	// collect batch result, and merge it into main result
-	go func() {
-		for result := range results {
-			if len(result) > 0 {
-				total += len(result) // filtered out number of rows in the batch
-				var sb strings.Builder
-				for _, s := range result {
-					sb.WriteString(strings.Join(s, sep))
-					sb.WriteByte('\n')
-				}
-				if out {
-					bw.WriteString(sb.String())
-				} else {
-					fmt.Print(sb.String()) // has \n, so use fmt.Print
-				}
+			if out {
+				bw.WriteString(sb.String())
+			} else {
+				fmt.Print(sb.String()) // has \n, so use fmt.Print
 			}
-
-			wg.Done() // indicate work done
 		}
-	}()
-
-	n := 0             // batch number of rows
-	var batch []string //batch holder
-	for br.Scan() {
-		batch = append(batch, br.Text())
-		n++
-		if n > BatchRowsPerStat { // 2000 rows per batch
-			wg.Add(1)
-			jobs <- batch
-			n = 0
-			batch = []string{}
-		}
-	}
-
-	if len(batch) > 0 {
-		wg.Add(1)
-		jobs <- batch
+	})
+	release()
+	if err != nil {
+		fmt.Println(err.Error())
 	}
-	close(jobs)
-
-	wg.Wait()
 
 	// delete out file if not saving
 	bw.Flush()
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+// Slice
+// records in [start, end) counted from 0, quote-aware.
+// with header, records are counted after the header, and the header is shown first.
+// a fresh index is used to seek near start, instead of reading from the beginning
+func Slice(file string, header bool, start int, end int) {
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv slice --help'.")
+		return
+	}
+	if start < 0 || end < start {
+		fmt.Print("Start should be non-negative and no more than end. Try command 'gsv slice --help'.")
+		return
+	}
+
+	f, err := os.Open(file)
+	utility.CheckErr(err)
+	defer f.Close()
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	// records in file, header included
+	if header {
+		writeRecord(w, headerRecord(f))
+		start++
+		end++
+	}
+
+	offset, skip := int64(0), start
+	if idx, fresh := utility.LoadIndex(file); fresh {
+		offset, skip = idx.Seek(start)
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	utility.CheckErr(err)
+	i := start - skip // current record
+
+	br := utility.NewRecordScanner(f)
+	for ; i < end && br.Scan(); i++ {
+		if i >= start {
+			writeRecord(w, br.Bytes())
+		}
+	}
+}
+
+// Tail
+// last n records, quote-aware, read backwards from the end of file.
+// with header, the header is shown first and is not counted in the last n records
+func Tail(file string, header bool, n int) {
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv tail --help'.")
+		return
+	}
+	if n < 0 {
+		fmt.Print("Number of records should be non-negative. Try command 'gsv tail --help'.")
+		return
+	}
+
+	f, err := os.Open(file)
+	utility.CheckErr(err)
+	defer f.Close()
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	offset, err := utility.TailOffset(f, int64(utility.FileSize(file)), n)
+	utility.CheckErr(err)
+	if header {
+		writeRecord(w, headerRecord(f))
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	utility.CheckErr(err)
+	br := utility.NewRecordScanner(f)
+	// fewer records than n, header is already shown
+	if header && offset == 0 {
+		br.Scan()
+	}
+	for br.Scan() {
+		writeRecord(w, br.Bytes())
+	}
+}
+
+// first record of file, the header
+func headerRecord(f *os.File) []byte {
+	br := utility.NewRecordScanner(f)
+	br.Scan()
+	return utility.CopyBytes(br.Bytes())
+}
+
+func writeRecord(w *bufio.Writer, record []byte) {
+	w.Write(record)
+	w.WriteByte('\n')
+}
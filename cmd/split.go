@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ribbondz/gsv/cmd/utility"
+)
+
+var (
+	SplitBlockBytes   = 1024 * 1024 // 1MB rows are sent to a writer at a time
+	SplitMaxOpenFiles = 256         // max open files shared by writers in hash mode
+)
+
+// content to append to a split file
+type splitWrite struct {
+	i       int // index of split file
+	content []byte
+}
+
+// names of split files by ratio, more than 3 parts are numbered
+var ratioPartNames = map[int][]string{
+	2: {"train", "test"},
+	3: {"train", "valid", "test"},
+}
+
+// splitWriter
+// rows are cached per file and sent to concurrent writers in blocks,
+// a file is always written by the same writer so that rows keep their order
+type splitWriter struct {
+	prefix  string
+	names   []string // names of split files, numbered if nil
+	ext     string
+	header  []byte
+	blocks  map[int][]byte // cached rows for each file
+	started map[int]bool   // files with header written
+	rows    map[int]int    // row count of each file
+	writers []chan splitWrite
+	wg      sync.WaitGroup
+}
+
+func Split(file string, header bool, sep string, rows int, size int, colPara string, parts int,
+	ratioPara string, seed int64, stratifyPara string, groupPara string, prefix string, ragged *utility.Ragged) {
+	var et utility.ElapsedTime
+	et.Start()
+
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv split --help'.")
+		return
+	}
+	// exactly one of rows, size, column and ratio
+	modes := 0
+	for _, set := range []bool{rows > 0, size > 0, colPara != "", ratioPara != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		fmt.Print("Split by one of rows, size, column or ratio. Try command 'gsv split --help'.")
+		return
+	}
+	var ratios []float64
+	if ratioPara != "" {
+		var err error
+		if ratios, err = splitRatios(ratioPara); err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	} else if stratifyPara != "" || groupPara != "" {
+		fmt.Print("Stratify and group are only used with ratio. Try command 'gsv split --help'.")
+		return
+	}
+	if colPara != "" && parts < 1 {
+		fmt.Print("Number of parts should be positive. Try command 'gsv split --help'.")
+		return
+	}
+
+	f, _ := os.Open(file)
+	defer f.Close()
+	br := utility.NewRecordScanner(f)
+
+	// column names and header
+	var names []string
+	var headerBytes []byte
+	if header {
+		br.Scan()
+		headerBytes = utility.CopyBytes(br.Bytes())
+		names = strings.Split(string(headerBytes), sep)
+	} else {
+		for i := 0; i < ColumnN(file, sep); i++ {
+			names = append(names, "col_"+strconv.Itoa(i+1))
+		}
+	}
+	col := -1
+	if colPara != "" {
+		c, err := utility.ColumnIndex(colPara, names)
+		if err != nil {
+			fmt.Print("Column syntax error. Try command 'gsv split --help'.")
+			return
+		}
+		col = c
+	}
+	stratify, group := -1, -1
+	if stratifyPara != "" {
+		c, err := utility.ColumnIndex(stratifyPara, names)
+		if err != nil {
+			fmt.Print("Stratify column syntax error. Try command 'gsv split --help'.")
+			return
+		}
+		stratify = c
+	}
+	if groupPara != "" {
+		c, err := utility.ColumnIndex(groupPara, names)
+		if err != nil {
+			fmt.Print("Group column syntax error. Try command 'gsv split --help'.")
+			return
+		}
+		group = c
+	}
+
+	if prefix == "" {
+		prefix = splitPrefix(file)
+	}
+	ext := filepath.Ext(file)
+	if ext == "" {
+		ext = ".txt"
+	}
+	if dir := filepath.Dir(prefix); dir != "" {
+		err := os.MkdirAll(dir, os.ModePerm)
+		utility.CheckErr(err)
+	}
+
+	w := newSplitWriter(prefix, ext, headerBytes)
+	var assigner *utility.RatioAssigner
+	if ratios != nil {
+		w.names = ratioPartNames[len(ratios)]
+		assigner = utility.NewRatioAssigner(ratios, seed, stratify, group)
+	}
+	headerN := 0
+	if header {
+		headerN = len(headerBytes) + 1
+	}
+	var (
+		N     = 0       // total rows
+		i     = 0       // current file in rows and size modes
+		bytes = headerN // bytes of current file in size mode
+	)
+	fix, finish := ragged.Filter(file, header)
+	for br.Scan() {
+		line := br.Bytes()
+		row, ok, err := fix(string(line))
+		if err != nil {
+			break
+		}
+		if !ok {
+			continue
+		}
+		if len(row) != len(line) {
+			line = []byte(row) // fixed by the ragged policy
+		}
+		switch {
+		case rows > 0:
+			if N > 0 && N%rows == 0 {
+				w.flush(i)
+				i++
+			}
+		case size > 0:
+			if bytes > headerN && bytes+len(line)+1 > size*1024*1024 {
+				w.flush(i)
+				i++
+				bytes = headerN
+			}
+			bytes += len(line) + 1
+		case ratios != nil:
+			i = assigner.Assign(strings.Split(string(line), sep))
+		default:
+			fields := strings.Split(string(line), sep)
+			key := ""
+			if col < len(fields) {
+				key = fields[col]
+			}
+			i = shortHashIndex(key, parts)
+		}
+		w.add(i, line)
+		N++
+	}
+	if err := br.Err(); err != nil {
+		fmt.Println(err)
+	}
+	w.close()
+	if err := finish(); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("Record count: %d, split into %d files with prefix: %s\n", N, len(w.rows), prefix)
+	if ratios != nil {
+		for j := range ratios {
+			fmt.Printf("%s: %d rows (%s%%)\n", filepath.Base(w.filename(j)), w.rows[j], percentString(w.rows[j], N))
+		}
+	}
+	et.EndAndPrint()
+}
+
+func newSplitWriter(prefix string, ext string, header []byte) *splitWriter {
+	w := &splitWriter{
+		prefix:  prefix,
+		ext:     ext,
+		header:  header,
+		blocks:  make(map[int][]byte),
+		started: make(map[int]bool),
+		rows:    make(map[int]int),
+	}
+	n := runtime.NumCPU()
+	if n > SplitMaxOpenFiles {
+		n = SplitMaxOpenFiles
+	}
+	w.writers = make([]chan splitWrite, n)
+	for i := range w.writers {
+		ch := make(chan splitWrite, 20)
+		w.writers[i] = ch
+		cache := newWriterCache(SplitMaxOpenFiles / n)
+		w.wg.Add(1)
+		go func() {
+			for t := range ch {
+				cache.write(w.filename(t.i), t.content)
+			}
+			cache.closeAll()
+			w.wg.Done()
+		}()
+	}
+	return w
+}
+
+// add
+// a row to file i, header is added before the first row
+func (w *splitWriter) add(i int, line []byte) {
+	b := w.blocks[i]
+	if !w.started[i] {
+		w.started[i] = true
+		if w.header != nil {
+			b = append(b, w.header...)
+			b = append(b, '\n')
+		}
+	}
+	b = append(b, line...)
+	b = append(b, '\n')
+	w.rows[i]++
+	if len(b) > SplitBlockBytes {
+		w.writers[i%len(w.writers)] <- splitWrite{i, b}
+		b = nil
+	}
+	w.blocks[i] = b
+}
+
+// flush
+// send cached rows of file i to writer
+func (w *splitWriter) flush(i int) {
+	if b := w.blocks[i]; len(b) > 0 {
+		w.writers[i%len(w.writers)] <- splitWrite{i, b}
+	}
+	delete(w.blocks, i)
+}
+
+// close
+// flush all cached rows, and wait until files are closed
+func (w *splitWriter) close() {
+	for i := range w.blocks {
+		w.flush(i)
+	}
+	for _, ch := range w.writers {
+		close(ch)
+	}
+	w.wg.Wait()
+}
+
+// filename
+// prefix followed by 4 digits file number from 1 or part name, and extension of source file
+func (w *splitWriter) filename(i int) string {
+	if w.names != nil {
+		return w.prefix + w.names[i] + w.ext
+	}
+	return fmt.Sprintf("%s%04d%s", w.prefix, i+1, w.ext)
+}
+
+// splitRatios
+// examples:
+// 0.8,0.2
+// 0.8,0.1,0.1
+// ratios must be positive and sum up to 1
+func splitRatios(arg string) (ratios []float64, err error) {
+	t := 0.0
+	for _, r := range strings.Split(arg, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(r), 64)
+		if err != nil || v <= 0 {
+			return ratios, errors.New("error split ratio: " + r)
+		}
+		ratios = append(ratios, v)
+		t += v
+	}
+	if len(ratios) < 2 {
+		return ratios, errors.New("at least two split ratios")
+	}
+	if math.Abs(t-1) > 1e-6 {
+		return ratios, errors.New("split ratios should sum up to 1")
+	}
+	return
+}
+
+// default prefix in working directory, e.g., data-split-current-time-
+func splitPrefix(file string) string {
+	wd, _ := os.Getwd()
+	file = strings.TrimSuffix(file, filepath.Ext(file))
+	file = utility.DirToFilename(file)
+	timeStr := time.Now().Format("20060102150405")
+	return filepath.Join(wd, file+"-split-"+timeStr+"-")
+}
@@ -2,28 +2,27 @@ package cmd
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
 	"github.com/ribbondz/gsv/cmd/utility"
 	"os"
-	"runtime"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 )
 
 const (
-	IsInt = iota
-	IsFloat
-	IsString
-	IsNull
+	IsInt            = utility.ColInt
+	IsFloat          = utility.ColFloat
+	IsString         = utility.ColString
+	IsNull           = utility.ColNull
 	BatchRowsPerStat = 2000 //rows per batch
 )
 
 type ColStats struct {
 	cType      int
 	nulls      int
+	valueN     int // number of not-null values
 	minLength  int
 	maxLength  int
 	intStats   IntColStats
@@ -50,7 +49,13 @@ type FloatColStats struct {
 	total float64
 }
 
-func Stats(file string, header bool, sep string) {
+// group statistics, one []ColStats for each unique key of the group-by columns
+type GroupStats struct {
+	n     int // number of rows in the group
+	stats []ColStats
+}
+
+func Stats(file string, header bool, sep string, by []int, out bool, maxGroups int, mmap bool, ragged *utility.Ragged) {
 	var et utility.ElapsedTime
 	et.Start()
 	// check file existence
@@ -58,77 +63,222 @@ func Stats(file string, header bool, sep string) {
 		fmt.Print("File does not exist. Try command 'gsv stats --help'.")
 		return
 	}
+	if len(by) > 0 {
+		// group-by statistics
+		statsByGroup(file, header, sep, by, out, maxGroups, mmap, ragged)
+	} else {
+		stat, names, totalN, release, err := columnStats(file, header, sep, mmap, ragged)
+		defer release()
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		PrintStats(stat, names, totalN)
+	}
+	et.EndAndPrint()
+}
+
+// statsOpen
+// guess column types, and read column names
+func statsOpen(file string, header bool, sep string, ragged *utility.Ragged) (colTypes []int, firstValue []string, names []string, err error) {
 	// column types
-	colTypes, firstValue, err := GuessColType(file, header, sep) // "" is string
+	colTypes, firstValue, err = GuessColType(file, header, sep, ragged) // "" is string
 	if err != nil {
-		fmt.Println(err.Error())
 		return
 	}
-	// stats initial
-	stat := statsInit(colTypes, firstValue)
-	// stats processing
-	f, _ := os.Open(file)
-	defer f.Close()
-	br := bufio.NewScanner(f)
-	// column names and header drop
-	var names []string
+	// column names
 	if header {
+		f, _ := os.Open(file)
+		br := bufio.NewScanner(f)
 		br.Scan()
 		names = strings.Split(br.Text(), sep)
+		f.Close()
 	} else {
 		for i := range colTypes {
 			names = append(names, "col"+strconv.Itoa(i+1))
 		}
 	}
-	jobs := make(chan []string, 20)
-	results := make(chan []ColStats, 20)
-	wg := &sync.WaitGroup{}
-	// worker, cpu number
-	for i := 0; i < runtime.NumCPU(); i++ {
-		go func() {
-			for job := range jobs {
-				results <- processRow(job, colTypes, firstValue, sep)
+	return
+}
+
+// ColumnStats
+// statistics (type, null, unique, min, max, ...) on every column of file
+func ColumnStats(file string, header bool, sep string) (stat []ColStats, names []string, totalN int, err error) {
+	stat, names, totalN, _, err = columnStats(file, header, sep, false, nil)
+	return
+}
+
+// columnStats
+// statistics read by memory mapping if mmap is set,
+// release must be called after the statistics are used, as string values are views of the mapping
+func columnStats(file string, header bool, sep string, mmap bool, ragged *utility.Ragged) (stat []ColStats, names []string, totalN int, release func(), err error) {
+	release = func() {}
+	colTypes, firstValue, names, err := statsOpen(file, header, sep, ragged)
+	if err != nil {
+		return
+	}
+	// stats initial
+	stat = statsInit(colTypes, firstValue)
+	// byte ranges of file are read and processed in parallel, results are merged in order
+	release, err = utility.ParallelRead(file, header, mmap, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return processRow(rows, colTypes, firstValue, sep)
+	}, func(result interface{}, n int) {
+		totalN += n
+		stat = mergeStats(stat, result.([]ColStats))
+	})
+	return
+}
+
+// NumericRange
+// min and max of a numeric column, ok is false for other columns
+func (s ColStats) NumericRange() (min, max float64, ok bool) {
+	if s.valueN == 0 {
+		return
+	}
+	switch s.cType {
+	case IsInt:
+		return float64(s.intStats.min), float64(s.intStats.max), true
+	case IsFloat:
+		return s.floatStats.min, s.floatStats.max, true
+	}
+	return
+}
+
+// statsByGroup
+// statistics for each unique key of the group-by columns,
+// batch results are maps from group key to statistics, merged in the collector.
+// the number of groups is capped by maxGroups to keep memory bounded.
+func statsByGroup(file string, header bool, sep string, by []int, out bool, maxGroups int, mmap bool, ragged *utility.Ragged) {
+	colTypes, firstValue, names, err := statsOpen(file, header, sep, ragged)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	for _, c := range by {
+		if c >= len(colTypes) {
+			fmt.Printf("Group-by column %d out of range, file has %d columns.\n", c, len(colTypes))
+			return
+		}
+	}
+
+	groups := make(map[string]*GroupStats)
+	tooMany := false
+	totalN := 0
+	// byte ranges of file are read and processed in parallel, results are merged in order
+	release, err := utility.ParallelRead(file, header, mmap, BatchRowsPerStat, ragged, func(rows []string) interface{} {
+		return processRowByGroup(rows, colTypes, firstValue, sep, by)
+	}, func(result interface{}, n int) {
+		totalN += n
+		if !tooMany {
+			groups = mergeGroupStats(groups, result.(map[string]*GroupStats))
+			// stop merging, the remaining batches are only drained
+			if maxGroups > 0 && len(groups) > maxGroups {
+				tooMany = true
+				groups = nil
 			}
-		}()
+		}
+	})
+	// group keys and string statistics are views of the mapping
+	defer release()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if tooMany {
+		fmt.Printf("Too many groups: more than %d unique keys. Try a larger --max-groups, or fewer group-by columns.\n", maxGroups)
+		return
+	}
+
+	// sorted group keys
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
 	}
-	// collect result
-	go func() {
-		for result := range results {
-			stat = mergeStats(stat, result)
-			wg.Done()
+	sort.Strings(keys)
+
+	var byNames []string
+	for _, c := range by {
+		byNames = append(byNames, names[c])
+	}
+
+	// long-format csv: group columns, then the statistics table
+	if out {
+		table := [][]string{append(append([]string{}, byNames...), statsTableHeader...)}
+		for _, k := range keys {
+			for _, row := range statsTable(groups[k].stats, names) {
+				table = append(table, append(strings.Split(k, sep), row...))
+			}
+		}
+		outFile := utility.OutFilename(file, "stats")
+		utility.SaveFile(outFile, table)
+		fmt.Println("Statistics table saved to: ", outFile)
+	} else {
+		for _, k := range keys {
+			var desc []string
+			for i, v := range strings.Split(k, sep) {
+				desc = append(desc, byNames[i]+"="+v)
+			}
+			fmt.Printf("Group: %s\n", strings.Join(desc, ", "))
+			PrintStats(groups[k].stats, names, groups[k].n)
+			fmt.Println()
 		}
-	}()
-	// reading file in main thread
-	var batch []string
-	var totalN = 0
-	var n = 0
-	for br.Scan() {
-		totalN++
-		batch = append(batch, br.Text())
-		n++
-		if n > BatchRowsPerStat {
-			wg.Add(1)
-			jobs <- batch
-			n = 0
-			batch = []string{}
+	}
+	fmt.Printf("Total records: %d, total groups: %d\n", totalN, len(keys))
+}
+
+// statistics of batch rows for each group,
+// the group key is the group-by fields joined by separator
+func processRowByGroup(lines []string, colTypes []int, firstValue []string, sep string, by []int) map[string]*GroupStats {
+	rows := make(map[string][]string)
+	var fields []string // reused across rows
+	for _, line := range lines {
+		fields = utility.NormalizeFields(utility.SplitInto(line, sep, fields))
+		k := groupKey(fields, by, sep)
+		rows[k] = append(rows[k], line)
+	}
+	r := make(map[string]*GroupStats)
+	for k, v := range rows {
+		r[k] = &GroupStats{n: len(v), stats: processRow(v, colTypes, firstValue, sep)}
+	}
+	return r
+}
+
+// merge batch group statistics into main result
+func mergeGroupStats(dst, s map[string]*GroupStats) map[string]*GroupStats {
+	for k, b := range s {
+		if a, ok := dst[k]; ok {
+			a.n += b.n
+			a.stats = mergeStats(a.stats, b.stats)
+		} else {
+			dst[k] = b
 		}
 	}
-	if len(batch) > 0 {
-		wg.Add(1)
-		jobs <- batch
+	return dst
+}
+
+// group key of a row, missing fields are taken as empty value
+func groupKey(fields []string, by []int, sep string) string {
+	key := make([]string, len(by))
+	for i, c := range by {
+		if c < len(fields) {
+			key[i] = fields[c]
+		}
 	}
-	close(jobs)
-	wg.Wait()
-	PrintStats(stat, names, totalN)
-	et.EndAndPrint()
+	return strings.Join(key, sep)
 }
 
 // len(lines) > 0
 func processRow(lines []string, colTypes []int, firstValue []string, sep string) []ColStats {
 	stats := statsInit(colTypes, firstValue)
+	var fields []string // reused across rows
 	for _, line := range lines {
-		fields := strings.Split(line, sep)
+		fields = utility.NormalizeFields(utility.SplitInto(line, sep, fields))
 		for i, field := range fields {
+			// fields beyond the header are ignored
+			if i >= len(stats) {
+				break
+			}
 			cs := &stats[i]
 			l := len(field)
 			if l < cs.minLength {
@@ -138,38 +288,46 @@ func processRow(lines []string, colTypes []int, firstValue []string, sep string)
 				cs.maxLength = l
 			}
 			// null
-			if field == "" || field == "NA" || field == "Na" || field == "na" || field == "Null" || field == "NULL" {
+			if utility.IsNullValue(field) {
 				cs.nulls++
 			} else {
+				// the first not-null value initializes min and max
+				first := cs.valueN == 0
 				switch cs.cType {
 				case IsString:
-					if field < cs.strStats.min {
+					if first || field < cs.strStats.min {
 						cs.strStats.min = field
-					} else if field > cs.strStats.max {
+					}
+					if first || field > cs.strStats.max {
 						cs.strStats.max = field
 					}
 					cs.strStats.uniqueMap[field] = 0
+					cs.valueN++
 				case IsInt:
 					if v, err := strconv.ParseInt(field, 10, 64); err == nil {
 						b := int(v)
-						if b < cs.intStats.min {
+						if first || b < cs.intStats.min {
 							cs.intStats.min = b
-						} else if b > cs.intStats.max {
+						}
+						if first || b > cs.intStats.max {
 							cs.intStats.max = b
 						}
 						cs.intStats.total += b
 						cs.intStats.uniqueMap[b] = 0
+						cs.valueN++
 					} else {
 						fmt.Printf("Parsing error: Column %d has mixed types.", i+1)
 					}
 				case IsFloat:
 					if b, err := strconv.ParseFloat(field, 64); err == nil {
-						if b < cs.floatStats.min {
+						if first || b < cs.floatStats.min {
 							cs.floatStats.min = b
-						} else if b > cs.floatStats.max {
+						}
+						if first || b > cs.floatStats.max {
 							cs.floatStats.max = b
 						}
 						cs.floatStats.total += b
+						cs.valueN++
 					} else {
 						fmt.Printf("Parsing error: Column %d has mixed types.", i+1)
 					}
@@ -191,16 +349,23 @@ func mergeStats(dst []ColStats, s []ColStats) []ColStats {
 		if a.maxLength < b.maxLength {
 			a.maxLength = b.maxLength
 		}
+		// b has no value, only nulls
+		if b.valueN == 0 {
+			continue
+		}
+		// a has no value, min and max come from b
+		if a.valueN == 0 {
+			a.strStats.min, a.strStats.max = b.strStats.min, b.strStats.max
+			a.intStats.min, a.intStats.max = b.intStats.min, b.intStats.max
+			a.floatStats.min, a.floatStats.max = b.floatStats.min, b.floatStats.max
+		}
+		a.valueN += b.valueN
 		switch a.cType {
 		case IsString:
-			if a.strStats.min == "" {
-				a.strStats.min = b.strStats.min
-			} else if a.strStats.min > b.strStats.min {
+			if a.strStats.min > b.strStats.min {
 				a.strStats.min = b.strStats.min
 			}
-			if a.strStats.max == "" {
-				a.strStats.max = b.strStats.max
-			} else if a.strStats.max < b.strStats.max {
+			if a.strStats.max < b.strStats.max {
 				a.strStats.max = b.strStats.max
 			}
 			for k, _ := range b.strStats.uniqueMap {
@@ -231,56 +396,76 @@ func mergeStats(dst []ColStats, s []ColStats) []ColStats {
 }
 
 func PrintStats(stat []ColStats, names []string, totalN int) {
-	// avoid zero division
-	if totalN == 0 {
-		totalN++
-	}
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"col", "type", "null", "unique", "min", "max", "mean", "min_length", "max_length"})
+	table.SetHeader(statsTableHeader)
 	table.SetBorder(true)
+	table.AppendBulk(statsTable(stat, names))
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.SetCaption(true, "Total records: "+strconv.Itoa(totalN))
+	table.Render()
+}
+
+var statsTableHeader = []string{"col", "type", "null", "unique", "min", "max", "mean", "min_length", "max_length"}
+
+// statsTable
+// transform column statistics into rows of a table,
+// the mean is averaged over not-null values
+func statsTable(stat []ColStats, names []string) (r [][]string) {
 	for i, s := range stat {
+		// columns with only nulls have no min, max and mean
+		min, max, mean := "-", "-", "-"
 		switch s.cType {
 		case IsString:
-			table.Append([]string{
+			if s.valueN > 0 {
+				min, max = s.strStats.min, s.strStats.max
+			}
+			r = append(r, []string{
 				names[i],
 				"string",
 				strconv.Itoa(s.nulls),
 				strconv.Itoa(len(s.strStats.uniqueMap)),
-				s.strStats.min,
-				s.strStats.max,
+				min,
+				max,
 				"-",
 				strconv.Itoa(s.minLength),
 				strconv.Itoa(s.maxLength),
 			})
 		case IsInt:
-			table.Append([]string{
+			if s.valueN > 0 {
+				min, max = strconv.Itoa(s.intStats.min), strconv.Itoa(s.intStats.max)
+				mean = strconv.FormatFloat(float64(s.intStats.total)/float64(s.valueN), 'f', 4, 64)
+			}
+			r = append(r, []string{
 				names[i],
 				"int",
 				strconv.Itoa(s.nulls),
 				strconv.Itoa(len(s.intStats.uniqueMap)),
-				strconv.Itoa(s.intStats.min),
-				strconv.Itoa(s.intStats.max),
-				strconv.FormatFloat(float64(s.intStats.total)/float64(totalN-s.nulls), 'f', 4, 64),
+				min,
+				max,
+				mean,
 				strconv.Itoa(s.minLength),
 				strconv.Itoa(s.maxLength),
 			})
 		case IsFloat:
-			table.Append([]string{
+			if s.valueN > 0 {
+				min = strconv.FormatFloat(s.floatStats.min, 'f', 4, 64)
+				max = strconv.FormatFloat(s.floatStats.max, 'f', 4, 64)
+				mean = strconv.FormatFloat(s.floatStats.total/float64(s.valueN), 'f', 4, 64)
+			}
+			r = append(r, []string{
 				names[i],
 				"float",
 				strconv.Itoa(s.nulls),
 				"-",
-				strconv.FormatFloat(s.floatStats.min, 'f', 4, 64),
-				strconv.FormatFloat(s.floatStats.max, 'f', 4, 64),
-				strconv.FormatFloat(s.floatStats.total/float64(totalN-s.nulls), 'f', 4, 64),
+				min,
+				max,
+				mean,
 				strconv.Itoa(s.minLength),
 				strconv.Itoa(s.maxLength),
 			})
 		}
 	}
-	table.SetAlignment(tablewriter.ALIGN_RIGHT)
-	table.SetCaption(true, "Total records: "+strconv.Itoa(totalN))
-	table.Render()
+	return
 }
 
 func statsInit(colTypes []int, firstValue []string) (stat []ColStats) {
@@ -310,9 +495,14 @@ func statsInit(colTypes []int, firstValue []string) (stat []ColStats) {
 	return
 }
 
-func GuessColType(file string, header bool, sep string) ([]int, []string, error) {
+// GuessColType
+// column types and first not-null values, guessed from the first rows.
+// ragged rows are fixed by the policy of ragged, or not used if it is nil,
+// the error policy stops at the first ragged row
+func GuessColType(file string, header bool, sep string, ragged *utility.Ragged) ([]int, []string, error) {
 	var (
 		guessN     = 10000
+		lineN      = 1 // line number of the next row, header included
 		line       = ""
 		cType      []int
 		firstValue []string
@@ -327,53 +517,45 @@ func GuessColType(file string, header bool, sep string) ([]int, []string, error)
 	// open file
 	f, _ := os.Open(file)
 	defer f.Close()
-	br := bufio.NewScanner(f)
+	br := utility.NewRecordScanner(f)
 	if header {
 		br.Scan()
-		br.Bytes()
+		lineN += utility.RecordLines(br.Text())
 	}
-	// read
+	// read, rows of unequal length are fixed by the policy
 	for br.Scan() && guessN > 0 {
 		guessN--
 		line = br.Text()
+		rowLine := lineN
+		lineN += utility.RecordLines(line)
 		fields = strings.Split(line, sep)
-		if len(fields) != len(cType) {
-			return []int{}, []string{}, errors.New("rows have unequal length")
+		if n := utility.CountFields(line, sep); n != len(cType) {
+			if ragged == nil {
+				continue
+			}
+			if ragged.Policy == utility.RaggedError {
+				return nil, nil, ragged.Error(rowLine, n, len(cType))
+			}
+			fixed, ok := ragged.Fix(line, n, len(cType))
+			if !ok {
+				continue
+			}
+			fields = strings.Split(fixed, sep)
+		}
+		fields = utility.NormalizeFields(fields)
+		if len(fields) > len(cType) {
+			fields = fields[:len(cType)] // long rows kept by pad
 		}
 		for i, field := range fields {
 			// skip null values
-			if field == "" || field == "NA" || field == "Na" || field == "na" || field == "Null" || field == "NULL" {
+			if utility.IsNullValue(field) {
 				continue
 			}
 			// obtain first not-null value
 			if firstValue[i] == "" && len(strings.TrimSpace(field)) > 0 {
 				firstValue[i] = field
 			}
-			// if a column has a value "05"
-			// it is a string field, other than int
-			if len(field) > 1 && field[0:1] == "0" && !strings.Contains(field, ".") {
-				cType[i] = IsString
-				continue
-			}
-			// string is always string
-			if cType[i] == IsString {
-				continue
-			}
-			// is int
-			if _, err := strconv.Atoi(field); err == nil {
-				if cType[i] == IsNull {
-					cType[i] = IsInt
-				}
-				continue
-			}
-			// is floated
-			if _, err := strconv.ParseFloat(field, 64); err == nil {
-				if cType[i] == IsNull || cType[i] == IsInt {
-					cType[i] = IsFloat
-				}
-				continue
-			}
-			cType[i] = IsString
+			cType[i] = utility.GuessFieldType(cType[i], field)
 		}
 	}
 	return cType, firstValue, nil
@@ -0,0 +1,67 @@
+package utility
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	AggCount = iota
+	AggSum
+	AggMean
+	AggMin
+	AggMax
+	AggCountDistinct
+)
+
+var aggFuncs = map[string]int{
+	"count":          AggCount,
+	"sum":            AggSum,
+	"mean":           AggMean,
+	"avg":            AggMean,
+	"min":            AggMin,
+	"max":            AggMax,
+	"count_distinct": AggCountDistinct,
+}
+
+type Agg struct {
+	Func int
+	Col  int    // -1 for count(), which counts rows
+	Name string // output column name, e.g., sum(amount)
+}
+
+// ParseAggArg
+// examples:
+// sum(amount),mean(price),count()
+// count_distinct(3),min(ts),max(ts)
+// columns are referred to by header name or by index
+func ParseAggArg(arg string, names []string) (r []Agg, err error) {
+	for _, a := range strings.Split(arg, ",") {
+		a = strings.TrimSpace(a)
+		// avoid    sum(a),  =>   ['sum(a)', '']
+		if len(a) == 0 {
+			continue
+		}
+		open := strings.Index(a, "(")
+		if open < 1 || !strings.HasSuffix(a, ")") {
+			return r, errors.New("error aggregation syntax: " + a)
+		}
+		f, ok := aggFuncs[strings.ToLower(a[:open])]
+		if !ok {
+			return r, errors.New("unknown aggregation function: " + a[:open])
+		}
+		agg := Agg{Func: f, Col: -1, Name: a}
+		if c := strings.TrimSpace(a[open+1 : len(a)-1]); c != "" {
+			if agg.Col, err = ColumnIndex(c, names); err != nil {
+				return r, err
+			}
+		} else if f != AggCount {
+			return r, errors.New("aggregation needs a column: " + a)
+		}
+		r = append(r, agg)
+	}
+	if len(r) == 0 {
+		return r, errors.New("no aggregation")
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package utility
+
+import (
+	"testing"
+)
+
+func TestAggArgParse(t *testing.T) {
+	names := []string{"region", "amount", "price"}
+	aggs, err := ParseAggArg("sum(amount), mean(2),count(),count_distinct(region),", names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trueAggs := []Agg{
+		{AggSum, 1, "sum(amount)"},
+		{AggMean, 2, "mean(2)"},
+		{AggCount, -1, "count()"},
+		{AggCountDistinct, 0, "count_distinct(region)"},
+	}
+	if len(aggs) != len(trueAggs) {
+		t.Fatal("agg arg parse error.")
+	}
+	for i := range aggs {
+		if aggs[i] != trueAggs[i] {
+			t.Error("agg arg parse error.")
+		}
+	}
+
+	for _, arg := range []string{"", "sum()", "median(1)", "sum(unknown)", "sum(amount"} {
+		if _, err := ParseAggArg(arg, names); err == nil {
+			t.Errorf("agg arg %s should not be parsed.", arg)
+		}
+	}
+}
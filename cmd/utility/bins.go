@@ -0,0 +1,106 @@
+package utility
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EqualWidthEdges
+// n bins of equal width between min and max, n+1 edges
+func EqualWidthEdges(min, max float64, n int) (edges []float64) {
+	// one value only, avoid zero width bins
+	if max == min {
+		max = min + 1
+	}
+	width := (max - min) / float64(n)
+	for i := 0; i < n; i++ {
+		edges = append(edges, min+width*float64(i))
+	}
+	return append(edges, max)
+}
+
+// QuantileEdges
+// n bins with (nearly) equal number of values, duplicated edges are dropped
+func QuantileEdges(values []float64, n int) (edges []float64) {
+	if len(values) == 0 {
+		return
+	}
+	sort.Float64s(values)
+	for i := 0; i <= n; i++ {
+		e := values[(len(values)-1)*i/n]
+		if len(edges) == 0 || e > edges[len(edges)-1] {
+			edges = append(edges, e)
+		}
+	}
+	if len(edges) == 1 {
+		edges = append(edges, edges[0]+1)
+	}
+	return
+}
+
+// ParseEdges
+// examples:
+// 0,10,20,50
+// edges must be increasing
+func ParseEdges(arg string) (edges []float64, err error) {
+	for _, e := range strings.Split(arg, ",") {
+		e = strings.TrimSpace(e)
+		if len(e) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(e, 64)
+		if err != nil {
+			return edges, errors.New("error bin edge: " + e)
+		}
+		if len(edges) > 0 && v <= edges[len(edges)-1] {
+			return edges, errors.New("bin edges must be increasing")
+		}
+		edges = append(edges, v)
+	}
+	if len(edges) < 2 {
+		return edges, errors.New("at least two bin edges")
+	}
+	return
+}
+
+// BinIndex
+// bins are [a, b), and the last bin is [a, b] to include max.
+// -1 for values below the first edge, len(edges)-1 for values above the last edge.
+func BinIndex(edges []float64, v float64) int {
+	last := len(edges) - 1
+	if v < edges[0] {
+		return -1
+	}
+	if v > edges[last] {
+		return last
+	}
+	if v == edges[last] {
+		return last - 1
+	}
+	return sort.Search(last, func(i int) bool { return edges[i+1] > v })
+}
+
+// BinLabel
+// [a, b) for bin i, the last bin is [a, b]
+func BinLabel(edges []float64, i int) string {
+	last := len(edges) - 1
+	switch {
+	case i < 0:
+		return "< " + FormatEdge(edges[0])
+	case i >= last:
+		return "> " + FormatEdge(edges[last])
+	case i == last-1:
+		return "[" + FormatEdge(edges[i]) + ", " + FormatEdge(edges[i+1]) + "]"
+	}
+	return "[" + FormatEdge(edges[i]) + ", " + FormatEdge(edges[i+1]) + ")"
+}
+
+// FormatEdge
+// at most 4 decimals, trailing zeros are dropped
+func FormatEdge(e float64) string {
+	s := strconv.FormatFloat(e, 'f', 4, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
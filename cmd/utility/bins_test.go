@@ -0,0 +1,19 @@
+package utility
+
+import (
+	"testing"
+)
+
+func TestBinIndex(t *testing.T) {
+	edges := EqualWidthEdges(0, 10, 5) // 0, 2, 4, 6, 8, 10
+	values := []float64{-1, 0, 1.9, 2, 9.9, 10, 10.1}
+	trueIndex := []int{-1, 0, 0, 1, 4, 4, 5}
+	trueLabel := []string{"< 0", "[0, 2)", "[0, 2)", "[2, 4)", "[8, 10]", "[8, 10]", "> 10"}
+
+	for i, v := range values {
+		b := BinIndex(edges, v)
+		if b != trueIndex[i] || BinLabel(edges, b) != trueLabel[i] {
+			t.Errorf("value %v is in bin %d %s, should be %d %s.", v, b, BinLabel(edges, b), trueIndex[i], trueLabel[i])
+		}
+	}
+}
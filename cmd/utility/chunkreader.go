@@ -0,0 +1,335 @@
+package utility
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	ChunkMinBytes  = 1024 * 1024      // 1MB
+	ChunkMaxBytes  = 1024 * 1024 * 64 // 64MB
+	StrayLineBytes = 80               // bytes of a line with stray quotes shown in report
+)
+
+// Chunk
+// byte range [Start, End) of a file, aligned to record boundaries
+type Chunk struct {
+	Start int64
+	End   int64
+}
+
+// FileChunks
+// about n chunks of the records after header.
+// boundaries are taken from a fresh index if there is one,
+// otherwise quotes are counted in parallel to know whether a boundary is in a quoted field,
+// and each boundary is moved forward to the start of next record.
+func FileChunks(file string, header bool, n int) ([]Chunk, error) {
+	size := int64(FileSize(file))
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// data start after header
+	var start int64 = 0
+	if header {
+		if start, err = recordEnd(f, 0, false); err != nil {
+			return nil, err
+		}
+	}
+	if start >= size {
+		return nil, nil
+	}
+
+	chunkSize := (size - start) / int64(n)
+	if chunkSize < ChunkMinBytes {
+		chunkSize = ChunkMinBytes
+	}
+	if chunkSize > ChunkMaxBytes {
+		chunkSize = ChunkMaxBytes
+	}
+
+	// boundaries of records
+	var bounds []int64
+	if idx, fresh := LoadIndex(file); fresh {
+		last := start
+		for _, o := range idx.Offsets {
+			if o-last >= chunkSize && o < size {
+				bounds = append(bounds, o)
+				last = o
+			}
+		}
+	} else {
+		var tentative []int64
+		for b := start + chunkSize; b < size; b += chunkSize {
+			tentative = append(tentative, b)
+		}
+		parities, err := quoteParities(file, tentative)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range tentative {
+			e, err := recordEnd(f, b, parities[i])
+			if err != nil {
+				return nil, err
+			}
+			if e < size && (len(bounds) == 0 || e > bounds[len(bounds)-1]) {
+				bounds = append(bounds, e)
+			}
+		}
+	}
+
+	var chunks []Chunk
+	for _, b := range bounds {
+		chunks = append(chunks, Chunk{start, b})
+		start = b
+	}
+	return append(chunks, Chunk{start, size}), nil
+}
+
+// quoteParities
+// whether the number of quotes before each offset is odd, i.e., the offset is in a quoted field.
+// quotes between offsets are counted in parallel
+func quoteParities(file string, offsets []int64) ([]bool, error) {
+	counts := make([]int, len(offsets))
+	errs := make([]error, len(offsets))
+	wg := &sync.WaitGroup{}
+	var from int64 = 0
+	for i, to := range offsets {
+		wg.Add(1)
+		go func(i int, from, to int64) {
+			defer wg.Done()
+			f, err := os.Open(file)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer f.Close()
+			buf := make([]byte, 1024*1024)
+			r := io.NewSectionReader(f, from, to-from)
+			for {
+				n, err := r.Read(buf)
+				counts[i] += bytes.Count(buf[:n], []byte{'"'})
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, from, to)
+		from = to
+	}
+	wg.Wait()
+
+	parities := make([]bool, len(offsets))
+	t := 0
+	for i, c := range counts {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		t += c
+		parities[i] = t%2 == 1
+	}
+	return parities, nil
+}
+
+// recordEnd
+// offset after the first line terminator out of quotes from offset,
+// or file size if there is none
+func recordEnd(f *os.File, offset int64, inQuote bool) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.ReadAt(buf, offset)
+		for i, c := range buf[:n] {
+			switch c {
+			case '"':
+				inQuote = !inQuote
+			case '\n':
+				if !inQuote {
+					return offset + int64(i) + 1, nil
+				}
+			}
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+	}
+}
+
+// ReadChunk
+// records in a chunk in batches of batchRows rows.
+// records read as single lines for unbalanced quotes are added to strays, if it is not nil
+func ReadChunk(file string, c Chunk, batchRows int, strays *StrayQuotes, handle func(rows []string)) error {
+	return readChunk(file, c, batchRows, false, strays, handle)
+}
+
+func readChunk(file string, c Chunk, batchRows int, raw bool, strays *StrayQuotes, handle func(rows []string)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := NewRecordScanner(io.NewSectionReader(f, c.Start, c.End-c.Start))
+	offset := c.Start // offset of next record
+	br.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, stray, ok := nextRecordEnd(data, atEOF)
+		if !ok {
+			return 0, nil, nil
+		}
+		record := data[:advance]
+		if stray && strays != nil {
+			strays.add(offset, record)
+		}
+		offset += int64(advance)
+		if !raw {
+			record = bytes.TrimSuffix(bytes.TrimSuffix(record, []byte{'\n'}), []byte{'\r'})
+		}
+		return advance, record, nil
+	})
+	var batch []string
+	for br.Scan() {
+		batch = append(batch, br.Text())
+		if len(batch) >= batchRows {
+			handle(batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		handle(batch)
+	}
+	if err := br.Err(); err != nil {
+		return fmt.Errorf("error reading record at byte %d: %v", offset, err)
+	}
+	return nil
+}
+
+// ChunkedRead
+// chunks of file are read by one worker per cpu, each seeking to its byte range,
+// process is called on batches of rows in workers,
+// collect is called on batch results and batch row numbers in file order in the calling goroutine.
+// chunks in flight are limited, so that results waiting for earlier chunks are bounded.
+// records read as single lines for unbalanced quotes are reported to stderr
+func ChunkedRead(file string, header bool, batchRows int, process func(rows []string) interface{}, collect func(result interface{}, n int)) error {
+	return chunkedRead(file, header, batchRows, false, process, collect)
+}
+
+// ChunkedReadRaw
+// like ChunkedRead, records keep their line terminators
+func ChunkedReadRaw(file string, header bool, batchRows int, process func(rows []string) interface{}, collect func(result interface{}, n int)) error {
+	return chunkedRead(file, header, batchRows, true, process, collect)
+}
+
+func chunkedRead(file string, header bool, batchRows int, raw bool, process func(rows []string) interface{}, collect func(result interface{}, n int)) error {
+	chunks, err := FileChunks(file, header, runtime.NumCPU()*4)
+	if err != nil {
+		return err
+	}
+	strays := &StrayQuotes{}
+	defer strays.Report()
+	return runChunks(chunks, func(c Chunk, handle func(rows []string)) error {
+		return readChunk(file, c, batchRows, raw, strays, handle)
+	}, process, collect)
+}
+
+// StrayQuotes
+// records read as single lines for unbalanced quotes, and the first of them in file
+type StrayQuotes struct {
+	mu     sync.Mutex
+	N      int
+	Offset int64 // byte offset of the first one
+	Line   string
+}
+
+func (s *StrayQuotes) add(offset int64, line []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.N++
+	if s.N == 1 || offset < s.Offset {
+		line = bytes.TrimRight(line, "\r\n")
+		s.Offset, s.Line = offset, string(line)
+		if len(line) > StrayLineBytes {
+			n := StrayLineBytes
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+			s.Line = string(line[:n]) + "..."
+		}
+	}
+}
+
+// Report
+// count and the first of stray quotes to stderr, nothing if there is none
+func (s *StrayQuotes) Report() {
+	if s.N > 0 {
+		fmt.Fprintf(os.Stderr, "Unbalanced quotes: %d records are read as single lines, the first at byte %d: %s. Try command 'gsv lint'.\n",
+			s.N, s.Offset, s.Line)
+	}
+}
+
+// runChunks
+// chunks are read by workers, and results are collected in order
+func runChunks(chunks []Chunk, read func(c Chunk, handle func(rows []string)) error,
+	process func(rows []string) interface{}, collect func(result interface{}, n int)) (err error) {
+	workers := runtime.NumCPU()
+
+	type chunkResult struct {
+		i       int
+		results []interface{}
+		ns      []int // rows of each batch
+		err     error
+	}
+	jobs := make(chan int)
+	done := make(chan chunkResult, workers)
+	window := make(chan bool, workers*2)
+
+	go func() {
+		for i := range chunks {
+			window <- true
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				r := chunkResult{i: i}
+				r.err = read(chunks[i], func(rows []string) {
+					r.results = append(r.results, process(rows))
+					r.ns = append(r.ns, len(rows))
+				})
+				done <- r
+			}
+		}()
+	}
+
+	// collect in file order
+	pending := make(map[int]chunkResult)
+	for next := 0; next < len(chunks); {
+		r := <-done
+		pending[r.i] = r
+		for p, ok := pending[next]; ok; p, ok = pending[next] {
+			delete(pending, next)
+			if p.err != nil && err == nil {
+				err = p.err
+			}
+			for j, result := range p.results {
+				collect(result, p.ns[j])
+			}
+			next++
+			<-window
+		}
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package utility
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestChunkedRead(t *testing.T) {
+	// about 3MB, records with line breaks in quoted fields
+	var sb strings.Builder
+	sb.WriteString("id,text\n")
+	for i := 0; i < 100000; i++ {
+		sb.WriteString(strconv.Itoa(i) + ",\"a\nb,\"\"c\"\"\n\"\n")
+	}
+	file := filepath.Join(t.TempDir(), "a.csv")
+	if err := os.WriteFile(file, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			idx, err := BuildIndex(file, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := idx.Save(IndexFilename(file)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		chunks, err := FileChunks(file, true, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) < 2 {
+			t.Errorf("file should be read in chunks, got %d", len(chunks))
+		}
+
+		var records []string
+		err = ChunkedRead(file, true, 100, func(rows []string) interface{} {
+			return rows
+		}, func(result interface{}, n int) {
+			records = append(records, result.([]string)...)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, _ := os.Open(file)
+		br := bufio.NewScanner(f)
+		br.Split(ScanRecords)
+		br.Scan() // header
+		i := 0
+		for br.Scan() {
+			if i >= len(records) || records[i] != br.Text() {
+				t.Fatalf("record %d differs in chunked read", i)
+			}
+			i++
+		}
+		f.Close()
+		if i != len(records) {
+			t.Errorf("chunked read %d records, expected %d", len(records), i)
+		}
+	}
+}
@@ -55,7 +55,7 @@ func ParseColArg(col string) (r ColArgs, err error) {
 			}
 		}
 	}
-	if len(r.Include) == 0 && len(r.Include) > 0 {
+	if len(r.Include) == 0 && len(r.Exclude) > 0 {
 		r.All = true
 	}
 	return
@@ -77,3 +77,47 @@ func AllIncludedCols(col ColArgs, totalColumn int) (r []int) {
 	}
 	return
 }
+
+// ColumnIndex
+// a column referred to by header name, or by index.
+// names generated for files without header, col_3 or col3, refer to the third column
+func ColumnIndex(c string, names []string) (int, error) {
+	for i, name := range names {
+		if name == c {
+			return i, nil
+		}
+	}
+	if strings.HasPrefix(c, "col") {
+		if v, err := strconv.Atoi(strings.TrimPrefix(c[3:], "_")); err == nil && v >= 1 && v <= len(names) {
+			return v - 1, nil
+		}
+	}
+	v, err := strconv.Atoi(c)
+	if err != nil || v < 0 || v >= len(names) {
+		return -1, errors.New("unknown column: " + c)
+	}
+	return v, nil
+}
+
+// ParseColNames
+// examples:
+// region,product
+// 0,2
+// columns are referred to by header name or by index, order is kept
+func ParseColNames(col string, names []string) (r []int, err error) {
+	for _, c := range strings.Split(col, ",") {
+		c = strings.TrimSpace(c)
+		if len(c) == 0 {
+			continue
+		}
+		v, err := ColumnIndex(c, names)
+		if err != nil {
+			return r, err
+		}
+		r = append(r, v)
+	}
+	if len(r) == 0 {
+		return r, errors.New("no column selected")
+	}
+	return
+}
@@ -18,7 +18,7 @@ func TestColArgParse(t *testing.T) {
 	for i := range testArgs {
 		p, _ := ParseColArg(testArgs[i])
 		a := trueArgs[i]
-		if p.all != a.all || !SliceIntEqual(p.include, a.include) || !SliceIntEqual(p.exclude, a.exclude) {
+		if p.All != a.All || !SliceIntEqual(p.Include, a.Include) || !SliceIntEqual(p.Exclude, a.Exclude) {
 			t.Error("col arg parse error.")
 		}
 	}
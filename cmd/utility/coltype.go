@@ -0,0 +1,69 @@
+package utility
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// column types, a column widens from null to int, float and string
+const (
+	ColInt = iota
+	ColFloat
+	ColString
+	ColNull
+)
+
+// GuessFieldType
+// column type t updated by a not-null field,
+// a column widens from null to int, float and string
+func GuessFieldType(t int, field string) int {
+	// if a column has a value "05"
+	// it is a string field, other than int
+	if len(field) > 1 && field[0:1] == "0" && !strings.Contains(field, ".") {
+		return ColString
+	}
+	// string is always string
+	if t == ColString {
+		return t
+	}
+	// is int
+	if _, err := strconv.Atoi(field); err == nil {
+		if t == ColNull {
+			return ColInt
+		}
+		return t
+	}
+	// is floated, inf and nan are strings
+	if _, ok := ParseFinite(field); ok {
+		if t == ColNull || t == ColInt {
+			return ColFloat
+		}
+		return t
+	}
+	return ColString
+}
+
+// ParseFinite
+// a float other than infinities and nan
+func ParseFinite(field string) (float64, bool) {
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// ColTypeName
+// name of a column type
+func ColTypeName(t int) string {
+	switch t {
+	case ColInt:
+		return "int"
+	case ColFloat:
+		return "float"
+	case ColString:
+		return "string"
+	}
+	return "null"
+}
@@ -0,0 +1,69 @@
+package utility
+
+import (
+	"strings"
+)
+
+// SplitQuoted
+// fields of a record split by sep out of quotes, appended to dst.
+// quoted fields are kept as they are, e.g., "a,b" is one field with its quotes.
+// ok is false if quotes are unbalanced, or a field has quotes but is not a well-formed quoted field
+func SplitQuoted(record string, sep string, dst []string) (fields []string, ok bool) {
+	fields, ok = dst[:0], true
+	start, inQuote := 0, false
+	for i := 0; i < len(record); i++ {
+		switch {
+		case record[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && strings.HasPrefix(record[i:], sep):
+			fields = append(fields, record[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	fields = append(fields, record[start:])
+	if inQuote {
+		return fields, false
+	}
+	for _, f := range fields {
+		if strings.IndexByte(f, '"') >= 0 && !IsQuotedField(f) {
+			return fields, false
+		}
+	}
+	return fields, true
+}
+
+// CountFields
+// number of fields of a record split by sep out of quotes, as SplitQuoted does
+func CountFields(record string, sep string) int {
+	n, inQuote := 1, false
+	for i := 0; i < len(record); i++ {
+		switch {
+		case record[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && strings.HasPrefix(record[i:], sep):
+			n++
+			i += len(sep) - 1
+		}
+	}
+	return n
+}
+
+// IsQuotedField
+// a field enclosed in quotes, with quotes in it doubled
+func IsQuotedField(f string) bool {
+	if len(f) < 2 || f[0] != '"' || f[len(f)-1] != '"' {
+		return false
+	}
+	inner := f[1 : len(f)-1]
+	return strings.Count(inner, `"`) == 2*strings.Count(inner, `""`)
+}
+
+// Unquote
+// value of a field, enclosing quotes are removed and doubled quotes are unescaped
+func Unquote(f string) string {
+	if !IsQuotedField(f) {
+		return f
+	}
+	return strings.ReplaceAll(f[1:len(f)-1], `""`, `"`)
+}
@@ -0,0 +1,25 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitQuoted(t *testing.T) {
+	records := []string{`a,"b,c",d`, `1,"x ""y""",2`, `1,"x`, `1,x"y,2`, `a;;"b;c"`}
+	seps := []string{",", ",", ",", ",", ";;"}
+	trueFields := []string{`a|"b,c"|d`, `1|"x ""y"""|2`, `1|"x`, `1|x"y,2`, `a|"b;c"`}
+	trueOk := []bool{true, true, false, false, true}
+	for i, r := range records {
+		fields, ok := SplitQuoted(r, seps[i], nil)
+		if strings.Join(fields, "|") != trueFields[i] || ok != trueOk[i] {
+			t.Errorf("record %s is split into %q (%v), expected %s (%v)", r, fields, ok, trueFields[i], trueOk[i])
+		}
+		if n := CountFields(r, seps[i]); n != len(fields) {
+			t.Errorf("record %s has %d fields counted, expected %d", r, n, len(fields))
+		}
+	}
+	if v := Unquote(`"x ""y"""`); v != `x "y"` {
+		t.Errorf("unquoted value %s, expected %s", v, `x "y"`)
+	}
+}
@@ -0,0 +1,61 @@
+package utility
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxFilenameBytes = 100 // leave room for suffixes within the common 255 bytes limit
+)
+
+// reserved device names on Windows, with or without extension
+var reservedFilenames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename
+// a value as a file name portable across systems:
+// path separators, characters invalid on Windows and control characters are replaced by '_',
+// trailing dots and spaces are dropped, reserved names are prefixed by '_',
+// and long names are truncated on a utf-8 boundary.
+func SanitizeFilename(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		switch {
+		case r < 32 || r == 127:
+			sb.WriteByte('_')
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			sb.WriteByte('_')
+		case r == utf8.RuneError:
+			sb.WriteByte('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+
+	// truncate long names
+	if len(name) > MaxFilenameBytes {
+		n := MaxFilenameBytes
+		for n > 0 && !utf8.RuneStart(name[n]) {
+			n--
+		}
+		name = name[:n]
+	}
+
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "_empty"
+	}
+	base := name
+	if i := strings.IndexByte(base, '.'); i > -1 {
+		base = base[:i]
+	}
+	if reservedFilenames[strings.ToUpper(strings.TrimSpace(base))] {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,26 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	values := []string{"beijing", "a/b\\c", `a:b*c?d"e<f>g|h`, "a\tb\x00c", "a\xffb", "abc. .", "...", "", "CON", "con.txt", "Lpt1", "CONSOLE", "北京"}
+	trueNames := []string{"beijing", "a_b_c", "a_b_c_d_e_f_g_h", "a_b_c", "a_b", "abc", "_empty", "_empty", "_CON", "_con.txt", "_Lpt1", "CONSOLE", "北京"}
+	for i, v := range values {
+		if r := SanitizeFilename(v); r != trueNames[i] {
+			t.Errorf("file name of %q is %q, expected %q", v, r, trueNames[i])
+		}
+	}
+
+	// long names are truncated on a utf-8 boundary
+	long := strings.Repeat("北", MaxFilenameBytes)
+	r := SanitizeFilename(long)
+	if len(r) > MaxFilenameBytes || r != strings.Repeat("北", MaxFilenameBytes/3) {
+		t.Errorf("long name is truncated to %d bytes: %q", len(r), r)
+	}
+	if r := SanitizeFilename(strings.Repeat("a", MaxFilenameBytes+10)); len(r) != MaxFilenameBytes {
+		t.Errorf("long name is truncated to %d bytes", len(r))
+	}
+}
@@ -2,7 +2,9 @@ package utility
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 func FileSize(file string) int {
@@ -30,3 +32,14 @@ func DirToFilename(dir string) string {
 	}
 	return dir
 }
+
+// OutFilename
+// output filename in working directory,
+// data.txt with tag "stats" has the out filename data-stats-current-time.txt
+func OutFilename(file string, tag string) string {
+	wd, _ := os.Getwd()
+	file = strings.TrimSuffix(file, filepath.Ext(file))
+	file = DirToFilename(file)
+	timeStr := time.Now().Format("20060102150405")
+	return filepath.Join(wd, file+"-"+tag+"-"+timeStr+".txt")
+}
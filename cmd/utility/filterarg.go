@@ -1,6 +1,7 @@
 package utility
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 )
@@ -67,10 +68,16 @@ func NewFilter(arg string, columnN int) (*Filter, error) {
 
 // handle one filter condition
 func (f *Filter) handleOneFilter(arg string) error {
-	splits := strings.Split(arg, "=")
+	splits := strings.SplitN(arg, "=", 2)
+	if len(splits) != 2 {
+		return errors.New("error filter syntax: " + arg)
+	}
+	splits[1] = Normalize(splits[1]) // compared with normalized fields
 	c, err := strconv.Atoi(splits[0])
 	if err != nil {
 		return err
+	} else if c < 0 || c >= len(f.Filters) {
+		return errors.New("filter column out of range: " + splits[0])
 	} else {
 		fc := &f.Filters[c]
 		fc.Applicable = true
@@ -101,7 +108,7 @@ func (f *Filter) handleOneFilter(arg string) error {
 	return nil
 }
 
-// apply filter to row
+// apply filter to row, fields are normalized before comparison
 func (f *Filter) FilterOneRowSatisfy(row []string) bool {
 	switch f.Op {
 	case IsNull: // no filter specified
@@ -109,12 +116,16 @@ func (f *Filter) FilterOneRowSatisfy(row []string) bool {
 	case IsAnd:
 		for i, filter := range f.Filters {
 			if filter.Applicable {
+				if i >= len(row) {
+					return false
+				}
+				field := Normalize(row[i])
 				if filter.T == IsStringFilter {
-					if !SliceContainsString(filter.StringV, row[i]) {
+					if !SliceContainsString(filter.StringV, field) {
 						return false
 					}
 				} else {
-					v, err := strconv.ParseFloat(row[i], 64)
+					v, err := strconv.ParseFloat(field, 64)
 					if err != nil || !SliceContainsFloat(filter.FloatV, v) {
 						return false
 					}
@@ -124,11 +135,12 @@ func (f *Filter) FilterOneRowSatisfy(row []string) bool {
 		return true
 	case IsOr:
 		for i, filter := range f.Filters {
-			if filter.Applicable {
-				if filter.T == IsStringFilter && SliceContainsString(filter.StringV, row[i]) {
+			if filter.Applicable && i < len(row) {
+				field := Normalize(row[i])
+				if filter.T == IsStringFilter && SliceContainsString(filter.StringV, field) {
 					return true
 				} else {
-					v, err := strconv.ParseFloat(row[i], 64)
+					v, err := strconv.ParseFloat(field, 64)
 					if err == nil && SliceContainsFloat(filter.FloatV, v) {
 						return true
 					}
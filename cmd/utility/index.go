@@ -0,0 +1,193 @@
+package utility
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"io"
+	"os"
+)
+
+const (
+	IndexVersion = 1
+	IndexExt     = ".gsvi"
+	IndexStep    = 10000 // default records between two offsets
+
+	RecordMaxLines = 1000             // lines of a quoted field, more is taken as an unbalanced quote
+	RecordMaxBytes = 64 * 1024 * 1024 // bytes of a record, more is taken as an unbalanced quote
+)
+
+// Index
+// sidecar index of a file, byte offsets of every Step records.
+// records are quote-aware, a line terminator in a quoted field does not end a record.
+// size and modification time of the file are kept to check staleness.
+type Index struct {
+	Version int
+	Size    int64
+	ModTime int64 // unix nano
+	Step    int
+	Records int     // records, header included
+	Lines   int     // lines, the last line without line terminator included
+	Offsets []int64 // Offsets[i] is the byte offset of record i*Step
+}
+
+// IndexFilename
+// data.csv has the index data.csv.gsvi
+func IndexFilename(file string) string {
+	return file + IndexExt
+}
+
+// BuildIndex
+// one pass over the file, keep the offset of every step records.
+// records are read as by NewRecordScanner, so that the index agrees with scanned records
+func BuildIndex(file string, step int) (*Index, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	idx := &Index{Version: IndexVersion, Size: info.Size(), ModTime: info.ModTime().UnixNano(), Step: step}
+	var offset int64 = 0
+	br := NewRecordScanner(bufio.NewReaderSize(f, 1024*1024))
+	br.Split(ScanRawRecords)
+	for br.Scan() {
+		record := br.Bytes()
+		if idx.Records%step == 0 {
+			idx.Offsets = append(idx.Offsets, offset)
+		}
+		idx.Records++
+		idx.Lines += bytes.Count(record, []byte{'\n'})
+		if record[len(record)-1] != '\n' {
+			idx.Lines++ // last line without line terminator
+		}
+		offset += int64(len(record))
+	}
+	if err := br.Err(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Save
+// gob encoded index
+func (idx *Index) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := gob.NewEncoder(w).Encode(idx); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadIndex
+// index of a file if it exists and is fresh, i.e., the file is not changed after indexing
+func LoadIndex(file string) (*Index, bool) {
+	f, err := os.Open(IndexFilename(file))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	var idx Index
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&idx); err != nil || idx.Version != IndexVersion {
+		return nil, false
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.Size() != idx.Size || info.ModTime().UnixNano() != idx.ModTime {
+		return nil, false
+	}
+	return &idx, true
+}
+
+// Seek
+// offset of the nearest indexed record no later than record,
+// and number of records to skip from there
+func (idx *Index) Seek(record int) (offset int64, skip int) {
+	if record <= 0 || len(idx.Offsets) == 0 {
+		return 0, record
+	}
+	i := record / idx.Step
+	if i >= len(idx.Offsets) {
+		i = len(idx.Offsets) - 1
+	}
+	return idx.Offsets[i], record - i*idx.Step
+}
+
+// NewRecordScanner
+// scanner of quote-aware records, line terminators dropped,
+// with a buffer large enough for records up to RecordMaxBytes
+func NewRecordScanner(r io.Reader) *bufio.Scanner {
+	br := bufio.NewScanner(r)
+	br.Buffer(make([]byte, 0, 64*1024), RecordMaxBytes)
+	br.Split(ScanRecords)
+	return br
+}
+
+// ScanRecords
+// split function of bufio.Scanner, a line terminator in a quoted field does not end a record.
+// like bufio.ScanLines, the line terminator is dropped
+func ScanRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, _, ok := nextRecordEnd(data, atEOF)
+	if !ok {
+		return 0, nil, nil
+	}
+	return advance, bytes.TrimSuffix(bytes.TrimSuffix(data[:advance], []byte{'\n'}), []byte{'\r'}), nil
+}
+
+// ScanRawRecords
+// like ScanRecords, the line terminator is kept
+func ScanRawRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, _, ok := nextRecordEnd(data, atEOF)
+	if !ok {
+		return 0, nil, nil
+	}
+	return advance, data[:advance], nil
+}
+
+// nextRecordEnd
+// length of the record at the start of data with its line terminator, false if more data is needed.
+// a quoted field over RecordMaxLines lines or RecordMaxBytes bytes, or open at the end of file,
+// is taken as an unbalanced quote: the record ends at its first line terminator (stray is true),
+// so that a stray quote does not merge the rest of file into one record
+func nextRecordEnd(data []byte, atEOF bool) (advance int, stray bool, ok bool) {
+	if atEOF && len(data) == 0 {
+		return 0, false, false
+	}
+	quotes, lines := 0, 0
+	first := -1 // end of the first line
+	for from := 0; ; {
+		i := bytes.IndexByte(data[from:], '\n')
+		if i < 0 {
+			switch {
+			case atEOF && (first < 0 || (quotes+bytes.Count(data[from:], []byte{'"'}))%2 == 0):
+				return len(data), false, true
+			case atEOF || first > 0 && len(data) >= RecordMaxBytes:
+				return first, true, true
+			}
+			return 0, false, false
+		}
+		quotes += bytes.Count(data[from:from+i], []byte{'"'})
+		from += i + 1
+		if quotes%2 == 0 {
+			return from, false, true
+		}
+		if first < 0 {
+			first = from
+		}
+		if lines++; lines > RecordMaxLines || from > RecordMaxBytes {
+			return first, true, true
+		}
+	}
+}
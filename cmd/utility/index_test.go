@@ -0,0 +1,97 @@
+package utility
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	content := "a,b\n1,\"x\ny\"\n2,z\r\n3,w\n4,v"
+	file := filepath.Join(t.TempDir(), "a.csv")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := BuildIndex(file, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Records != 5 || idx.Lines != 6 {
+		t.Errorf("index records %d, lines %d, expected 5 and 6", idx.Records, idx.Lines)
+	}
+	trueOffsets := []int64{0, int64(strings.Index(content, "2,z")), int64(strings.Index(content, "4,v"))}
+	if len(idx.Offsets) != len(trueOffsets) {
+		t.Fatalf("index offsets %v, expected %v", idx.Offsets, trueOffsets)
+	}
+	for i := range trueOffsets {
+		if idx.Offsets[i] != trueOffsets[i] {
+			t.Errorf("index offsets %v, expected %v", idx.Offsets, trueOffsets)
+		}
+	}
+	if offset, skip := idx.Seek(3); offset != trueOffsets[1] || skip != 1 {
+		t.Errorf("index seek error: %d, %d", offset, skip)
+	}
+
+	if err := idx.Save(IndexFilename(file)); err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh := LoadIndex(file); !fresh {
+		t.Error("index should be fresh.")
+	}
+	if err := os.WriteFile(file, []byte(content+"\n5,u"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh := LoadIndex(file); fresh {
+		t.Error("index should be stale.")
+	}
+}
+
+func TestScanRecords(t *testing.T) {
+	br := bufio.NewScanner(strings.NewReader("a,b\n1,\"x\ny\"\r\n2,z"))
+	br.Split(ScanRecords)
+	var records []string
+	for br.Scan() {
+		records = append(records, br.Text())
+	}
+	trueRecords := []string{"a,b", "1,\"x\ny\"", "2,z"}
+	if strings.Join(records, "|") != strings.Join(trueRecords, "|") {
+		t.Errorf("scan records %q, expected %q", records, trueRecords)
+	}
+}
+
+func TestScanRecordsStrayQuote(t *testing.T) {
+	// a stray quote is read as one line, instead of merging the rest of file
+	var sb strings.Builder
+	sb.WriteString("a,b\n1,x\"y\n")
+	for i := 0; i < RecordMaxLines*2; i++ {
+		sb.WriteString("2,z\n")
+	}
+	sb.WriteString("3,\"open\nend") // open quote at the end of file
+	br := NewRecordScanner(strings.NewReader(sb.String()))
+	var records []string
+	for br.Scan() {
+		records = append(records, br.Text())
+	}
+	if br.Err() != nil {
+		t.Fatal(br.Err())
+	}
+	if len(records) != RecordMaxLines*2+4 || records[1] != "1,x\"y" || records[2] != "2,z" ||
+		records[len(records)-2] != "3,\"open" || records[len(records)-1] != "end" {
+		t.Errorf("stray quotes are scanned into %d records, expected %d", len(records), RecordMaxLines*2+4)
+	}
+
+	// quoted fields within the limits, also at the end of file without line terminator
+	for _, data := range []string{"1,\"x\ny\"", "1,\"x\ny\"\n", "1,\"x\r\n\"\r\n"} {
+		advance, stray, ok := nextRecordEnd([]byte(data), true)
+		if advance != len(data) || stray || !ok {
+			t.Errorf("record %q ends at %d (stray %v), expected %d", data, advance, stray, len(data))
+		}
+	}
+	// more data is needed
+	if _, _, ok := nextRecordEnd([]byte("1,\"x\ny"), false); ok {
+		t.Error("an open quoted field should need more data.")
+	}
+}
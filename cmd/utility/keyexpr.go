@@ -0,0 +1,186 @@
+package utility
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KeyColumn = iota
+	KeySubstr
+	KeyDate
+	KeyBucket
+)
+
+var keyFuncs = map[string]int{
+	"substr": KeySubstr,
+	"date":   KeyDate,
+	"bucket": KeyBucket,
+}
+
+// date layouts tried in order
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"2006/01/02",
+	"2006/01/02 15:04:05",
+	"20060102",
+}
+
+// output layouts of date truncation
+var dateUnits = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"day":   "2006-01-02",
+	"hour":  "2006-01-02 15",
+}
+
+type KeyExpr struct {
+	Func  int
+	Col   int
+	Name  string  // name of the key, e.g., city, or ts_month for date(ts,"month")
+	Start int     // substr start
+	Len   int     // substr length
+	Unit  string  // date output layout
+	Width float64 // bucket width
+}
+
+// ParseKeyExpr
+// examples:
+// city,year
+// substr(city,0,3)
+// date(ts,"month"), unit is one of year, month, day and hour
+// bucket(amount,100), numeric range of width 100 as its lower bound, e.g., 200 for 250
+// columns are referred to by header name, by index, or by generated name as col3 or col_3
+func ParseKeyExpr(arg string, names []string) (r []KeyExpr, err error) {
+	for _, a := range splitTopLevel(arg) {
+		a = strings.TrimSpace(a)
+		if len(a) == 0 {
+			continue
+		}
+		open := strings.Index(a, "(")
+		if open < 0 {
+			c, err := ColumnIndex(a, names)
+			if err != nil {
+				return r, err
+			}
+			r = append(r, KeyExpr{Func: KeyColumn, Col: c, Name: names[c]})
+			continue
+		}
+		if open == 0 || !strings.HasSuffix(a, ")") {
+			return r, errors.New("error key expression syntax: " + a)
+		}
+		f, ok := keyFuncs[strings.ToLower(strings.TrimSpace(a[:open]))]
+		if !ok {
+			return r, errors.New("unknown key function: " + a[:open])
+		}
+		args := splitTopLevel(a[open+1 : len(a)-1])
+		for i := range args {
+			args[i] = strings.Trim(strings.TrimSpace(args[i]), `"'`)
+		}
+		e := KeyExpr{Func: f}
+		if e.Col, err = ColumnIndex(args[0], names); err != nil {
+			return r, err
+		}
+		switch f {
+		case KeySubstr:
+			if len(args) != 3 {
+				return r, errors.New("substr needs column, start and length: " + a)
+			}
+			e.Start, err = strconv.Atoi(args[1])
+			if err != nil || e.Start < 0 {
+				return r, errors.New("error substr start: " + a)
+			}
+			e.Len, err = strconv.Atoi(args[2])
+			if err != nil || e.Len < 1 {
+				return r, errors.New("error substr length: " + a)
+			}
+			e.Name = names[e.Col] + "_substr"
+		case KeyDate:
+			if len(args) != 2 {
+				return r, errors.New("date needs column and unit: " + a)
+			}
+			unit := strings.ToLower(args[1])
+			if e.Unit, ok = dateUnits[unit]; !ok {
+				return r, errors.New("date unit should be year, month, day or hour: " + a)
+			}
+			e.Name = names[e.Col] + "_" + unit
+		case KeyBucket:
+			if len(args) != 2 {
+				return r, errors.New("bucket needs column and width: " + a)
+			}
+			e.Width, err = strconv.ParseFloat(args[1], 64)
+			if err != nil || !(e.Width > 0) || math.IsInf(e.Width, 0) {
+				return r, errors.New("error bucket width: " + a)
+			}
+			e.Name = names[e.Col] + "_bucket"
+		}
+		r = append(r, e)
+	}
+	if len(r) == 0 {
+		return r, errors.New("no key expression")
+	}
+	return r, nil
+}
+
+// Eval
+// key of a field value, unparsable dates and non-numeric values of bucket are empty
+func (e KeyExpr) Eval(v string) string {
+	switch e.Func {
+	case KeySubstr:
+		runes := []rune(v)
+		if e.Start >= len(runes) {
+			return ""
+		}
+		end := e.Start + e.Len
+		if end > len(runes) {
+			end = len(runes)
+		}
+		return string(runes[e.Start:end])
+	case KeyDate:
+		v = strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.Format(e.Unit)
+			}
+		}
+		return ""
+	case KeyBucket:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return ""
+		}
+		// adding 0 turns -0 into 0
+		return strconv.FormatFloat(math.Floor(f/e.Width)*e.Width+0, 'f', -1, 64)
+	}
+	return v
+}
+
+// split by commas out of parentheses and quotes
+func splitTopLevel(s string) (r []string) {
+	depth, start := 0, 0
+	var quote rune
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			r = append(r, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(r, s[start:])
+}
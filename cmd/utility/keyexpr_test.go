@@ -0,0 +1,79 @@
+package utility
+
+import (
+	"testing"
+)
+
+func TestKeyExprParse(t *testing.T) {
+	names := []string{"city", "ts", "user_id"}
+	keys, err := ParseKeyExpr(`city, substr(city,0,3), date(ts,"month"), bucket(2,100),`, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trueKeys := []KeyExpr{
+		{Func: KeyColumn, Col: 0, Name: "city"},
+		{Func: KeySubstr, Col: 0, Name: "city_substr", Start: 0, Len: 3},
+		{Func: KeyDate, Col: 1, Name: "ts_month", Unit: "2006-01"},
+		{Func: KeyBucket, Col: 2, Name: "user_id_bucket", Width: 100},
+	}
+	if len(keys) != len(trueKeys) {
+		t.Fatal("key expression parse error.")
+	}
+	for i := range keys {
+		if keys[i] != trueKeys[i] {
+			t.Errorf("key expression parse error: %v", keys[i])
+		}
+	}
+
+	// expressions as in the request, on a file without header
+	names = []string{"col_1", "col_2", "col_3", "col_4", "col_5"}
+	keys, err = ParseKeyExpr(`substr(col3,0,10), date(col2,"month"), bucket(col5,100), bucket(col_5,0.5)`, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trueKeys = []KeyExpr{
+		{Func: KeySubstr, Col: 2, Name: "col_3_substr", Start: 0, Len: 10},
+		{Func: KeyDate, Col: 1, Name: "col_2_month", Unit: "2006-01"},
+		{Func: KeyBucket, Col: 4, Name: "col_5_bucket", Width: 100},
+		{Func: KeyBucket, Col: 4, Name: "col_5_bucket", Width: 0.5},
+	}
+	for i := range keys {
+		if keys[i] != trueKeys[i] {
+			t.Errorf("key expression parse error: %v", keys[i])
+		}
+	}
+
+	names = []string{"city", "ts", "user_id"}
+	for _, arg := range []string{"", "unknown", "substr(city,0)", "substr(city,-1,2)", `date(ts,"week")`, "bucket(city,0)", "bucket(city,-1)", "bucket(city,x)", "col4", "col0", "lower(city)", "substr(city,0,3"} {
+		if _, err := ParseKeyExpr(arg, names); err == nil {
+			t.Errorf("key expression %s should not be parsed.", arg)
+		}
+	}
+}
+
+func TestKeyExprEval(t *testing.T) {
+	cases := []struct {
+		e KeyExpr
+		v string
+		r string
+	}{
+		{KeyExpr{Func: KeyColumn}, "beijing", "beijing"},
+		{KeyExpr{Func: KeySubstr, Start: 0, Len: 3}, "beijing", "bei"},
+		{KeyExpr{Func: KeySubstr, Start: 1, Len: 10}, "北京市", "京市"},
+		{KeyExpr{Func: KeySubstr, Start: 5, Len: 1}, "abc", ""},
+		{KeyExpr{Func: KeyDate, Unit: "2006-01"}, "2024-03-05 10:00:00", "2024-03"},
+		{KeyExpr{Func: KeyDate, Unit: "2006"}, "2024/03/05", "2024"},
+		{KeyExpr{Func: KeyDate, Unit: "2006"}, "not a date", ""},
+		{KeyExpr{Func: KeyBucket, Width: 100}, "250", "200"},
+		{KeyExpr{Func: KeyBucket, Width: 100}, "99.9", "0"},
+		{KeyExpr{Func: KeyBucket, Width: 100}, "-0.5", "-100"},
+		{KeyExpr{Func: KeyBucket, Width: 100}, "-0", "0"},
+		{KeyExpr{Func: KeyBucket, Width: 0.5}, " 1.7 ", "1.5"},
+		{KeyExpr{Func: KeyBucket, Width: 100}, "abc", ""},
+	}
+	for _, c := range cases {
+		if r := c.e.Eval(c.v); r != c.r {
+			t.Errorf("key expression eval error: %s => %s, expected %s", c.v, r, c.r)
+		}
+	}
+}
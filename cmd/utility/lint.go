@@ -0,0 +1,239 @@
+package utility
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	LintFirstLines        = 5                 // line numbers kept of each issue
+	LintDuplicateMaxBytes = 256 * 1024 * 1024 // bytes of distinct rows kept to find duplicates
+)
+
+// checks of lint, in report order
+const (
+	LintFieldCount  = "inconsistent field counts"
+	LintLineEndings = "mixed line endings"
+	LintBOM         = "byte order mark"
+	LintUTF8        = "invalid utf-8"
+	LintQuotes      = "unbalanced quotes"
+	LintWhitespace  = "leading or trailing whitespace"
+	LintHeader      = "empty or duplicate header names"
+	LintThousands   = "thousand separators in numbers"
+	LintDuplicates  = "duplicate rows"
+)
+
+var LintChecks = []string{LintFieldCount, LintLineEndings, LintBOM, LintUTF8, LintQuotes,
+	LintWhitespace, LintHeader, LintThousands, LintDuplicates}
+
+// line endings, counted to find the mixed ones
+var LintEndings = []string{"CRLF", "LF", "CR"}
+
+// LintIssue
+// count and first line numbers of an issue
+type LintIssue struct {
+	Count int
+	Lines []int
+}
+
+func (s *LintIssue) Add(line int) {
+	s.Count++
+	if len(s.Lines) < LintFirstLines {
+		s.Lines = append(s.Lines, line)
+	}
+}
+
+// Merge
+// issue of a later batch, whose line numbers are offset by base
+func (s *LintIssue) Merge(o *LintIssue, base int) {
+	s.Count += o.Count
+	for _, l := range o.Lines {
+		s.Lines = append(s.Lines, base+l)
+	}
+	if len(s.Lines) > LintFirstLines {
+		s.Lines = s.Lines[:LintFirstLines]
+	}
+}
+
+// LintRow
+// hash of a row without line terminator, the row, and its line number in batch
+type LintRow struct {
+	Hash uint64
+	Row  string
+	Line int
+}
+
+// LintBatch
+// issues of a batch of rows, line numbers are counted from 1 in batch
+type LintBatch struct {
+	Lines     int
+	Issues    map[string]*LintIssue
+	Thousands map[int]int // columns with thousand separators, and their number of values
+	Rows      []LintRow
+}
+
+// LintProcessRows
+// issues of raw rows with line terminators
+func LintProcessRows(rows []string, sep string, fieldN int) *LintBatch {
+	b := &LintBatch{Issues: make(map[string]*LintIssue), Thousands: make(map[int]int)}
+	add := func(c string, line int) {
+		s, ok := b.Issues[c]
+		if !ok {
+			s = &LintIssue{}
+			b.Issues[c] = s
+		}
+		s.Add(line)
+	}
+	var fields []string // reused across rows
+	for _, raw := range rows {
+		line := b.Lines + 1
+		b.Lines += strings.Count(raw, "\n")
+		if !strings.HasSuffix(raw, "\n") {
+			b.Lines++ // last line without line terminator
+		}
+
+		// line endings, bare CRs are old Mac line endings
+		record := raw
+		switch {
+		case strings.HasSuffix(raw, "\r\n"):
+			add("CRLF", line)
+			record = raw[:len(raw)-2]
+		case strings.HasSuffix(raw, "\n"):
+			add("LF", line)
+			record = raw[:len(raw)-1]
+		}
+		if strings.Count(record, "\r") > strings.Count(record, "\r\n") {
+			add("CR", line)
+		}
+
+		if strings.HasPrefix(record, "\uFEFF") {
+			add(LintBOM, line)
+			record = record[len("\uFEFF"):]
+		}
+		if !utf8.ValidString(record) {
+			add(LintUTF8, line)
+		}
+
+		var ok bool
+		fields, ok = SplitQuoted(record, sep, fields)
+		if !ok {
+			add(LintQuotes, line)
+		}
+		if len(fields) != fieldN {
+			add(LintFieldCount, line)
+		}
+		whitespace := false
+		for i, field := range fields {
+			v := Unquote(field)
+			if len(v) > 0 && strings.TrimSpace(v) != v {
+				whitespace = true
+			}
+			if IsThousandSeparated(v) {
+				add(LintThousands, line)
+				b.Thousands[i]++
+			}
+		}
+		if whitespace {
+			add(LintWhitespace, line)
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(record))
+		b.Rows = append(b.Rows, LintRow{h.Sum64(), record, line})
+	}
+	return b
+}
+
+// LintMixedEndings
+// lines of endings other than the most common one are added to the mixed line endings issue,
+// the detail lists the count of each ending if there are more than one
+func LintMixedEndings(issues map[string]*LintIssue) (detail string) {
+	var endings []string
+	most := LintEndings[0]
+	for _, e := range LintEndings {
+		if issues[e].Count > 0 {
+			endings = append(endings, e+" "+strconv.Itoa(issues[e].Count))
+		}
+		if issues[e].Count > issues[most].Count {
+			most = e
+		}
+	}
+	if len(endings) > 1 {
+		for _, e := range LintEndings {
+			if e != most {
+				issues[LintLineEndings].Merge(issues[e], 0)
+			}
+		}
+		sort.Ints(issues[LintLineEndings].Lines)
+	}
+	return strings.Join(endings, ", ")
+}
+
+// DuplicateRows
+// rows seen before, found by hash and confirmed by comparing rows.
+// distinct rows are kept up to MaxBytes, after which new rows are only compared with kept ones
+type DuplicateRows struct {
+	MaxBytes int
+	Partial  bool // rows were not kept as MaxBytes is reached, their duplicates are missed
+	bytes    int
+	rows     map[uint64][]string
+}
+
+func NewDuplicateRows(maxBytes int) *DuplicateRows {
+	return &DuplicateRows{MaxBytes: maxBytes, rows: make(map[uint64][]string)}
+}
+
+// Add
+// true if row of hash has been added before
+func (d *DuplicateRows) Add(hash uint64, row string) bool {
+	kept := d.rows[hash]
+	for _, r := range kept {
+		if r == row {
+			return true
+		}
+	}
+	if d.bytes+len(row) > d.MaxBytes {
+		d.Partial = true
+		return false
+	}
+	d.bytes += len(row)
+	d.rows[hash] = append(kept, row)
+	return false
+}
+
+// IsThousandSeparated
+// a number with comma thousand separators, e.g., 1,234 or -1,234,567.89
+func IsThousandSeparated(v string) bool {
+	v = strings.TrimLeft(v, "+-")
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		if !isDigits(v[i+1:]) {
+			return false
+		}
+		v = v[:i]
+	}
+	groups := strings.Split(v, ",")
+	if len(groups) < 2 || len(groups[0]) > 3 || !isDigits(groups[0]) {
+		return false
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 || !isDigits(g) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package utility
+
+import (
+	"testing"
+)
+
+func TestLintProcessRows(t *testing.T) {
+	rows := []string{
+		"\uFEFF1,a,3\r\n",
+		"2,\"x\ny\",4\n",
+		"3, b ,\"1,234\"\n",
+		"4,\"c,5\n",
+		"5,\xff,6\n",
+		"6,d\re,7\n",
+		"7,e",
+	}
+	b := LintProcessRows(rows, ",", 3)
+	if b.Lines != 8 {
+		t.Errorf("batch has %d lines, expected 8", b.Lines)
+	}
+	trueLines := map[string][]int{
+		"CRLF":          {1},
+		"LF":            {2, 4, 5, 6, 7},
+		"CR":            {7},
+		LintBOM:         {1},
+		LintUTF8:        {6},
+		LintQuotes:      {5},
+		LintFieldCount:  {5, 8},
+		LintWhitespace:  {4},
+		LintThousands:   {4},
+		LintDuplicates:  nil,
+		LintLineEndings: nil,
+	}
+	for c, lines := range trueLines {
+		var got []int
+		if s, ok := b.Issues[c]; ok {
+			got = s.Lines
+		}
+		if !SliceIntEqual(got, lines) {
+			t.Errorf("lines of %s are %v, expected %v", c, got, lines)
+		}
+	}
+	if len(b.Thousands) != 1 || b.Thousands[2] != 1 {
+		t.Errorf("thousand separated columns are %v, expected column 2 once", b.Thousands)
+	}
+
+	// rows are kept without line terminator and BOM, with their lines
+	if len(b.Rows) != len(rows) || b.Rows[0].Row != "1,a,3" || b.Rows[1].Row != "2,\"x\ny\",4" || b.Rows[2].Line != 4 || b.Rows[6].Row != "7,e" {
+		t.Errorf("rows are %v", b.Rows)
+	}
+}
+
+func TestLintIssueMerge(t *testing.T) {
+	s := &LintIssue{}
+	s.Add(1)
+	s.Add(2)
+	o := &LintIssue{}
+	for l := 1; l <= LintFirstLines; l++ {
+		o.Add(l)
+	}
+	o.Add(LintFirstLines + 1)
+	s.Merge(o, 10)
+	if s.Count != LintFirstLines+3 || !SliceIntEqual(s.Lines, []int{1, 2, 11, 12, 13}) {
+		t.Errorf("merged issue is %v", s)
+	}
+}
+
+func TestLintMixedEndings(t *testing.T) {
+	issues := make(map[string]*LintIssue)
+	for _, c := range append(append([]string{}, LintChecks...), LintEndings...) {
+		issues[c] = &LintIssue{}
+	}
+	issues["LF"].Merge(&LintIssue{Count: 1, Lines: []int{5}}, 0)
+	if d := LintMixedEndings(issues); d != "LF 1" || issues[LintLineEndings].Count != 0 {
+		t.Errorf("one ending is mixed: %s", d)
+	}
+
+	// lines of the less common endings, in order
+	issues["CRLF"].Merge(&LintIssue{Count: 3, Lines: []int{1, 2, 3}}, 0)
+	issues["CR"].Merge(&LintIssue{Count: 1, Lines: []int{4}}, 0)
+	issues["LF"].Merge(&LintIssue{Count: 1, Lines: []int{6}}, 0)
+	d := LintMixedEndings(issues)
+	if d != "CRLF 3, LF 2, CR 1" || issues[LintLineEndings].Count != 3 || !SliceIntEqual(issues[LintLineEndings].Lines, []int{4, 5, 6}) {
+		t.Errorf("mixed endings are %s, %v", d, issues[LintLineEndings])
+	}
+}
+
+func TestDuplicateRows(t *testing.T) {
+	d := NewDuplicateRows(10)
+	if d.Add(1, "a,b") || !d.Add(1, "a,b") {
+		t.Error("a row added twice should be a duplicate.")
+	}
+	// rows of the same hash are compared
+	if d.Add(1, "c,d") || !d.Add(1, "c,d") {
+		t.Error("a row of a colliding hash should not be a duplicate of another row.")
+	}
+	// rows over max bytes are not kept
+	if d.Add(2, "e,f,g") || d.Add(2, "e,f,g") || !d.Partial {
+		t.Error("a row over max bytes should not be kept.")
+	}
+	if !d.Add(1, "a,b") {
+		t.Error("kept rows should still be compared after max bytes is reached.")
+	}
+}
+
+func TestIsThousandSeparated(t *testing.T) {
+	values := []string{"1,234", "-1,234,567.89", "+12,345", "1234", "1,23", "1234,567", "a,bcd", "1,234.", "1,234.5x", ",123", ""}
+	trueValues := []bool{true, true, true, false, false, false, false, false, false, false, false}
+	for i, v := range values {
+		if IsThousandSeparated(v) != trueValues[i] {
+			t.Errorf("%q is thousand separated: %v, expected %v", v, IsThousandSeparated(v), trueValues[i])
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package utility
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"unsafe"
+)
+
+var ErrMmapUnsupported = errors.New("mmap is not supported on this platform")
+
+// MappedFile
+// file mapped into memory read-only.
+// strings of records and fields read from it are views of the mapping without copy,
+// they are valid until Close.
+type MappedFile struct {
+	file string
+	Data []byte
+}
+
+// OpenMapped
+// ErrMmapUnsupported on platforms without mmap
+func OpenMapped(file string) (*MappedFile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m := &MappedFile{file: file}
+	if size := FileSize(file); size > 0 {
+		if m.Data, err = mmap(f, size); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *MappedFile) Close() error {
+	if m.Data == nil {
+		return nil
+	}
+	err := munmap(m.Data)
+	m.Data = nil
+	return err
+}
+
+// ChunkedRead
+// like ChunkedRead of a file, records are views of the mapping, stray quotes are reported alike.
+// the rows slice is reused by the worker after process returns, so process must not keep it,
+// while strings in it can be kept until Close.
+func (m *MappedFile) ChunkedRead(header bool, batchRows int, process func(rows []string) interface{}, collect func(result interface{}, n int)) error {
+	chunks, err := FileChunks(m.file, header, runtime.NumCPU()*4)
+	if err != nil {
+		return err
+	}
+	strays := &StrayQuotes{}
+	defer strays.Report()
+	return runChunks(chunks, func(c Chunk, handle func(rows []string)) error {
+		rows := make([]string, 0, batchRows)
+		data := m.Data[c.Start:c.End]
+		for pos := 0; pos < len(data); {
+			advance, stray, _ := nextRecordEnd(data[pos:], true)
+			record := data[pos : pos+advance]
+			if stray {
+				strays.add(c.Start+int64(pos), record)
+			}
+			pos += advance
+			record = bytes.TrimSuffix(bytes.TrimSuffix(record, []byte{'\n'}), []byte{'\r'})
+			rows = append(rows, bytesToString(record))
+			if len(rows) >= batchRows {
+				handle(rows)
+				rows = rows[:0]
+			}
+		}
+		if len(rows) > 0 {
+			handle(rows)
+		}
+		return nil
+	}, process, collect)
+}
+
+// ParallelRead
+// ChunkedRead on the memory mapping of file if mmap is set and supported, otherwise on the file.
+// ragged rows are handled by the policy of ragged if it is not nil, n of collect is the number of rows kept.
+// release must be called when results are no longer used, as strings of a mapping are views of the file.
+func ParallelRead(file string, header bool, mmap bool, batchRows int, ragged *Ragged,
+	process func(rows []string) interface{}, collect func(result interface{}, n int)) (release func(), err error) {
+	finish := func() error { return nil }
+	if ragged != nil {
+		process, collect, finish = ragged.wrap(file, header, process, collect)
+	}
+	release = func() {}
+	if mmap {
+		m, err := OpenMapped(file)
+		if err == nil {
+			release = func() { m.Close() }
+			err = m.ChunkedRead(header, batchRows, process, collect)
+			if e := finish(); err == nil {
+				err = e
+			}
+			return release, err
+		}
+		if err != ErrMmapUnsupported {
+			finish()
+			return release, err
+		}
+	}
+	err = ChunkedRead(file, header, batchRows, process, collect)
+	if e := finish(); err == nil {
+		err = e
+	}
+	return release, err
+}
+
+// SplitInto
+// like strings.Split, fields are appended to dst so that a buffer can be reused across rows
+func SplitInto(s string, sep string, dst []string) []string {
+	dst = dst[:0]
+	for {
+		i := strings.Index(s, sep)
+		if i < 0 {
+			return append(dst, s)
+		}
+		dst = append(dst, s[:i])
+		s = s[i+len(sep):]
+	}
+}
+
+// string view of bytes without copy, bytes must not be changed
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
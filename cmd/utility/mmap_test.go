@@ -0,0 +1,132 @@
+package utility
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// about 2MB, records with line breaks in quoted fields
+func writeRecordsFile(dir string, rows int) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("id,city,value,text\n")
+	for i := 0; i < rows; i++ {
+		text := "plain"
+		if i%100 == 0 {
+			text = "\"a\nb\""
+		}
+		sb.WriteString(strconv.Itoa(i) + ",city" + strconv.Itoa(i%50) + "," + strconv.Itoa(i*7) + "," + text + "\n")
+	}
+	file := filepath.Join(dir, "a.csv")
+	return file, os.WriteFile(file, []byte(sb.String()), 0644)
+}
+
+func TestMappedChunkedRead(t *testing.T) {
+	file, err := writeRecordsFile(t.TempDir(), 80000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenMapped(file)
+	if err == ErrMmapUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	read := func(chunked func(process func(rows []string) interface{}, collect func(result interface{}, n int)) error) (records []string) {
+		err := chunked(func(rows []string) interface{} {
+			// rows are reused by the worker, keep a copy of the slice
+			return append([]string{}, rows...)
+		}, func(result interface{}, n int) {
+			records = append(records, result.([]string)...)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	mapped := read(func(p func(rows []string) interface{}, c func(result interface{}, n int)) error {
+		return m.ChunkedRead(true, 1000, p, c)
+	})
+	plain := read(func(p func(rows []string) interface{}, c func(result interface{}, n int)) error {
+		return ChunkedRead(file, true, 1000, p, c)
+	})
+	if len(mapped) != 80000 || strings.Join(mapped, "|") != strings.Join(plain, "|") {
+		t.Errorf("mapped read %d records, different from chunked read of %d records", len(mapped), len(plain))
+	}
+}
+
+func TestSplitInto(t *testing.T) {
+	var dst []string
+	for _, s := range []string{"a,b,,c", "", ",", "abc"} {
+		dst = SplitInto(s, ",", dst)
+		if strings.Join(dst, "|") != strings.Join(strings.Split(s, ","), "|") {
+			t.Errorf("split %q into %q", s, dst)
+		}
+	}
+}
+
+// allocations per row of reading records and splitting fields,
+// rows are copied out of the file and fields are split into new slices
+func BenchmarkChunkedRead(b *testing.B) {
+	benchmarkRead(b, false)
+}
+
+// rows are views of the mapping and fields are split into a reused slice
+func BenchmarkMappedRead(b *testing.B) {
+	benchmarkRead(b, true)
+}
+
+func benchmarkRead(b *testing.B, mapped bool) {
+	rowN := 200000
+	file, err := writeRecordsFile(b.TempDir(), rowN)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m, err := OpenMapped(file)
+	if mapped && err != nil {
+		b.Skip(err)
+	}
+	if err == nil {
+		defer m.Close()
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	mallocs := stats.Mallocs
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		collect := func(result interface{}, rows int) {
+			n += result.(int)
+		}
+		if mapped {
+			err = m.ChunkedRead(true, 2000, func(rows []string) interface{} {
+				var fields []string
+				for _, row := range rows {
+					fields = SplitInto(row, ",", fields)
+				}
+				return len(rows)
+			}, collect)
+		} else {
+			err = ChunkedRead(file, true, 2000, func(rows []string) interface{} {
+				for _, row := range rows {
+					_ = strings.Split(row, ",")
+				}
+				return len(rows)
+			}, collect)
+		}
+		if err != nil || n != rowN {
+			b.Fatalf("read %d rows, %v", n, err)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&stats)
+	b.ReportMetric(float64(stats.Mallocs-mallocs)/float64(b.N*rowN), "allocs/row")
+}
@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package utility
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY, uint32(int64(size)>>32), uint32(size), nil)
+	if err != nil {
+		return nil, err
+	}
+	// the view keeps the mapping alive
+	defer syscall.CloseHandle(h)
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, err
+	}
+	// addr is the address of the view mapped by the system, outside of the go heap,
+	// so the garbage collector neither moves nor frees it and the conversion is valid until UnmapViewOfFile.
+	// vet reports any uintptr to pointer conversion as a possible misuse.
+	return unsafe.Slice((*byte)(unsafe.Pointer(addr)), size), nil //nolint:govet
+}
+
+func munmap(data []byte) error {
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&data[0])))
+}
@@ -0,0 +1,64 @@
+package utility
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// value normalization shared by all commands,
+// applied before counting, type guessing and filtering
+var (
+	normTrim       = false
+	normIgnoreCase = false
+	normNFC        = false
+	nullValues     = map[string]bool{"": true, "NA": true, "Na": true, "na": true, "Null": true, "NULL": true}
+)
+
+const (
+	DefaultNullValues = ",NA,Na,na,Null,NULL"
+)
+
+// SetNormalization
+// nulls is a comma separated list of null values, e.g., ",NA,NULL" where the first one is empty string.
+// null values are compared after normalization.
+func SetNormalization(trim bool, ignoreCase bool, nfc bool, nulls string) {
+	normTrim, normIgnoreCase, normNFC = trim, ignoreCase, nfc
+	nullValues = make(map[string]bool)
+	for _, v := range strings.Split(nulls, ",") {
+		nullValues[Normalize(v)] = true
+	}
+}
+
+// Normalize
+// trim spaces, lower case, and unicode NFC, according to options
+func Normalize(field string) string {
+	if normTrim {
+		field = strings.TrimSpace(field)
+	}
+	if normIgnoreCase {
+		field = strings.ToLower(field)
+	}
+	if normNFC {
+		field = norm.NFC.String(field)
+	}
+	return field
+}
+
+// NormalizeFields
+// normalize fields in place
+func NormalizeFields(fields []string) []string {
+	if !normTrim && !normIgnoreCase && !normNFC {
+		return fields
+	}
+	for i := range fields {
+		fields[i] = Normalize(fields[i])
+	}
+	return fields
+}
+
+// IsNullValue
+// a normalized field is null, default to "", NA, Na, na, Null, NULL
+func IsNullValue(field string) bool {
+	return nullValues[field]
+}
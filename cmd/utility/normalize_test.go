@@ -0,0 +1,56 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	// restore default normalization for other tests
+	defer SetNormalization(false, false, false, DefaultNullValues)
+
+	values := []string{" Abc ", "ABC", "Café", "Café", " NULL "}
+
+	SetNormalization(false, false, false, DefaultNullValues)
+	if r := strings.Join(NormalizeFields(append([]string{}, values...)), "|"); r != strings.Join(values, "|") {
+		t.Errorf("values are changed without normalization: %q", r)
+	}
+
+	SetNormalization(true, false, false, DefaultNullValues)
+	trueTrim := []string{"Abc", "ABC", "Café", "Café", "NULL"}
+	SetNormalization(true, true, true, DefaultNullValues)
+	trueAll := []string{"abc", "abc", "café", "café", "null"}
+	for i, v := range values {
+		SetNormalization(true, false, false, DefaultNullValues)
+		if r := Normalize(v); r != trueTrim[i] {
+			t.Errorf("trimmed %q is %q, expected %q", v, r, trueTrim[i])
+		}
+		SetNormalization(true, true, true, DefaultNullValues)
+		if r := Normalize(v); r != trueAll[i] {
+			t.Errorf("normalized %q is %q, expected %q", v, r, trueAll[i])
+		}
+	}
+}
+
+func TestIsNullValue(t *testing.T) {
+	defer SetNormalization(false, false, false, DefaultNullValues)
+
+	SetNormalization(false, false, false, DefaultNullValues)
+	values := []string{"", "NA", "NULL", "null", "-", "0"}
+	trueNull := []bool{true, true, true, false, false, false}
+	for i, v := range values {
+		if IsNullValue(v) != trueNull[i] {
+			t.Errorf("value %q is null: %v, expected %v", v, IsNullValue(v), trueNull[i])
+		}
+	}
+
+	// custom null values are normalized as fields are
+	SetNormalization(true, true, false, "-, N/A ")
+	values = []string{"-", "n/a", "", "NA"}
+	trueNull = []bool{true, true, false, false}
+	for i, v := range values {
+		if IsNullValue(Normalize(v)) != trueNull[i] {
+			t.Errorf("value %q is null with custom nulls: %v, expected %v", v, IsNullValue(Normalize(v)), trueNull[i])
+		}
+	}
+}
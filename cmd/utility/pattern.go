@@ -0,0 +1,119 @@
+package utility
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const PatternMaxShapes = 16 // distinct shapes kept, beyond which there is no common pattern
+
+// character classes of value shapes
+const (
+	classDigit = iota
+	classUpper
+	classLower
+	classLetter // letters other than ascii
+	classOther
+)
+
+var classRegexp = []string{`\d`, `[A-Z]`, `[a-z]`, `\p{L}`}
+
+// ValueShape
+// regular expression of a value, runs of digits, upper and lower case letters are classes,
+// with exact run lengths if exact is set, e.g., AB-0012 has the shape [A-Z]{2}-\d{4},
+// or one or more otherwise, [A-Z]+-\d+. other characters are literal
+func ValueShape(v string, exact bool) string {
+	var sb strings.Builder
+	class, run := -1, 0
+	flush := func() {
+		if run == 0 || class == classOther {
+			return
+		}
+		sb.WriteString(classRegexp[class])
+		if !exact {
+			sb.WriteByte('+')
+		} else if run > 1 {
+			sb.WriteString("{" + strconv.Itoa(run) + "}")
+		}
+	}
+	for _, r := range v {
+		c := runeClass(r)
+		if c == class && c != classOther {
+			run++
+			continue
+		}
+		flush()
+		class, run = c, 1
+		if c == classOther {
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	flush()
+	return "^" + sb.String() + "$"
+}
+
+func runeClass(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return classDigit
+	case r >= 'A' && r <= 'Z':
+		return classUpper
+	case r >= 'a' && r <= 'z':
+		return classLower
+	case unicode.IsLetter(r):
+		return classLetter
+	}
+	return classOther
+}
+
+// Shapes
+// distinct exact and loose shapes of values, up to PatternMaxShapes each
+type Shapes struct {
+	Exact map[string]bool
+	Loose map[string]bool
+}
+
+func NewShapes() *Shapes {
+	return &Shapes{Exact: make(map[string]bool), Loose: make(map[string]bool)}
+}
+
+func (s *Shapes) Add(v string) {
+	if len(s.Exact) <= PatternMaxShapes {
+		s.Exact[ValueShape(v, true)] = true
+	}
+	if len(s.Loose) <= PatternMaxShapes {
+		s.Loose[ValueShape(v, false)] = true
+	}
+}
+
+func (s *Shapes) Merge(o *Shapes) {
+	for k := range o.Exact {
+		if len(s.Exact) <= PatternMaxShapes {
+			s.Exact[k] = true
+		}
+	}
+	for k := range o.Loose {
+		if len(s.Loose) <= PatternMaxShapes {
+			s.Loose[k] = true
+		}
+	}
+}
+
+// Pattern
+// the common exact shape of all values, otherwise the common loose shape,
+// "" if values have different loose shapes
+func (s *Shapes) Pattern() string {
+	if len(s.Exact) == 1 {
+		for k := range s.Exact {
+			return k
+		}
+	}
+	if len(s.Loose) == 1 {
+		for k := range s.Loose {
+			return k
+		}
+	}
+	return ""
+}
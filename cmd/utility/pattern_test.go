@@ -0,0 +1,43 @@
+package utility
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestValueShape(t *testing.T) {
+	values := []string{"AB-0012", "Hello world", "3.14", "Zürich"}
+	trueExact := []string{`^[A-Z]{2}-\d{4}$`, `^[A-Z][a-z]{4} [a-z]{5}$`, `^\d\.\d{2}$`, `^[A-Z]\p{L}[a-z]{4}$`}
+	trueLoose := []string{`^[A-Z]+-\d+$`, `^[A-Z]+[a-z]+ [a-z]+$`, `^\d+\.\d+$`, `^[A-Z]+\p{L}+[a-z]+$`}
+	for i, v := range values {
+		exact, loose := ValueShape(v, true), ValueShape(v, false)
+		if exact != trueExact[i] || loose != trueLoose[i] {
+			t.Errorf("value %s has shapes %s and %s, expected %s and %s", v, exact, loose, trueExact[i], trueLoose[i])
+		}
+		for _, p := range []string{exact, loose} {
+			if !regexp.MustCompile(p).MatchString(v) {
+				t.Errorf("shape %s does not match value %s", p, v)
+			}
+		}
+	}
+}
+
+func TestShapesPattern(t *testing.T) {
+	s := NewShapes()
+	s.Add("AB-0012")
+	s.Add("CD-1234")
+	if p := s.Pattern(); p != `^[A-Z]{2}-\d{4}$` {
+		t.Errorf("pattern %s, expected exact shape", p)
+	}
+	o := NewShapes()
+	o.Add("XYZ-1")
+	s.Merge(o)
+	if p := s.Pattern(); p != `^[A-Z]+-\d+$` {
+		t.Errorf("pattern %s, expected loose shape", p)
+	}
+	o.Add("12")
+	s.Merge(o)
+	if p := s.Pattern(); p != "" {
+		t.Errorf("pattern %s, expected no pattern", p)
+	}
+}
@@ -0,0 +1,220 @@
+package utility
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// policies on ragged rows, whose number of fields differs from the first row
+const (
+	RaggedError    = "error"    // stop at the first ragged row
+	RaggedSkip     = "skip"     // drop ragged rows
+	RaggedPad      = "pad"      // pad short rows with empty fields, long rows are kept
+	RaggedTruncate = "truncate" // drop extra fields of long rows, short rows are kept
+)
+
+// Ragged
+// policy on ragged rows read in parallel or one by one.
+// fields are counted out of quotes, a separator in a quoted field does not split it.
+// ragged rows are counted, and written with their line numbers to BadRows if it is set.
+type Ragged struct {
+	Policy  string
+	BadRows string // file of ragged rows, optional
+	Sep     string
+	Fields  int // fields of a row, of the first row if 0
+	Count   int // ragged rows read
+}
+
+// raggedRow
+// a ragged row and its line in a batch
+type raggedRow struct {
+	line int
+	row  string
+}
+
+// result of a batch with ragged rows
+type raggedResult struct {
+	result interface{}
+	n      int // rows kept
+	lines  int // lines of the batch
+	bad    []raggedRow
+}
+
+// NewRagged
+// policy is one of error, skip, pad and truncate
+func NewRagged(policy string, badRows string, sep string) (*Ragged, error) {
+	switch policy {
+	case RaggedError, RaggedSkip, RaggedPad, RaggedTruncate:
+		return &Ragged{Policy: policy, BadRows: badRows, Sep: sep}, nil
+	}
+	return nil, errors.New("unknown ragged policy: " + policy + ", expected one of error, skip, pad and truncate")
+}
+
+// Fix
+// row of fields fixed by the policy to expected fields, false if the row is dropped
+func (r *Ragged) Fix(row string, fields int, expected int) (string, bool) {
+	switch r.Policy {
+	case RaggedSkip, RaggedError:
+		return row, false
+	case RaggedPad:
+		if fields < expected {
+			return row + strings.Repeat(r.Sep, expected-fields), true
+		}
+	case RaggedTruncate:
+		if fields > expected {
+			f, _ := SplitQuoted(row, r.Sep, nil)
+			return strings.Join(f[:expected], r.Sep), true
+		}
+	}
+	return row, true
+}
+
+// Error
+// error of the error policy on a ragged row of fields at line
+func (r *Ragged) Error(line int, fields int, expected int) error {
+	return errors.New("line " + strconv.Itoa(line) + " has " + strconv.Itoa(fields) +
+		" fields, expected " + strconv.Itoa(expected) + ". Try option --ragged skip, pad or truncate")
+}
+
+// RecordLines
+// physical lines of a record without its line terminator
+func RecordLines(row string) int {
+	return strings.Count(row, "\n") + 1
+}
+
+// raggedLog
+// ragged rows counted and written in file order
+type raggedLog struct {
+	r    *Ragged
+	f    *os.File
+	w    *bufio.Writer
+	line int // line number of the next row, from 1, header included
+	err  error
+}
+
+func (r *Ragged) newLog(file string, header bool) *raggedLog {
+	if r.Fields == 0 {
+		r.Fields = CountFields(string(HeaderBytes(file)), r.Sep)
+	}
+	r.Count = 0
+
+	l := &raggedLog{r: r, line: 1}
+	if header {
+		if f, err := os.Open(file); err == nil {
+			br := NewRecordScanner(f)
+			if br.Scan() {
+				l.line += RecordLines(br.Text())
+			}
+			f.Close()
+		}
+	}
+	if r.BadRows != "" {
+		if l.f, l.err = os.Create(r.BadRows); l.err == nil {
+			l.w = bufio.NewWriter(l.f)
+		}
+	}
+	return l
+}
+
+// add
+// a ragged row at line, the first one is the error of the error policy
+func (l *raggedLog) add(line int, row string) {
+	l.r.Count++
+	if l.r.Policy == RaggedError && l.err == nil {
+		l.err = l.r.Error(line, CountFields(row, l.r.Sep), l.r.Fields)
+	}
+	if l.w != nil {
+		l.w.WriteString(strconv.Itoa(line))
+		l.w.WriteString(l.r.Sep)
+		l.w.WriteString(row)
+		l.w.WriteByte('\n')
+	}
+}
+
+func (l *raggedLog) finish() error {
+	if l.w != nil {
+		l.w.Flush()
+		l.f.Close()
+	}
+	if l.r.Count > 0 {
+		fmt.Fprintf(os.Stderr, "Ragged rows: %d, policy %s\n", l.r.Count, l.r.Policy)
+	}
+	return l.err
+}
+
+// wrap
+// process and collect with ragged rows fixed in workers, and counted and written in file order.
+// finish reports ragged rows, and returns an error on the first ragged row by the error policy
+func (r *Ragged) wrap(file string, header bool, process func(rows []string) interface{}, collect func(result interface{}, n int)) (
+	func(rows []string) interface{}, func(result interface{}, n int), func() error) {
+	l := r.newLog(file, header)
+	if l.err != nil {
+		return process, collect, func() error { return l.err }
+	}
+
+	wrappedProcess := func(rows []string) interface{} {
+		var bad []raggedRow
+		lines := 0
+		kept := rows[:0] // rows are filtered in place
+		for _, row := range rows {
+			line := lines
+			lines += RecordLines(row)
+			fields := CountFields(row, r.Sep)
+			if fields != r.Fields {
+				bad = append(bad, raggedRow{line, row})
+				var ok bool
+				if row, ok = r.Fix(row, fields, r.Fields); !ok {
+					continue
+				}
+			}
+			kept = append(kept, row)
+		}
+		return raggedResult{process(kept), len(kept), lines, bad}
+	}
+	wrappedCollect := func(result interface{}, n int) {
+		res := result.(raggedResult)
+		for _, b := range res.bad {
+			l.add(l.line+b.line, b.row)
+		}
+		l.line += res.lines
+		if l.err == nil {
+			collect(res.result, res.n)
+		}
+	}
+	return wrappedProcess, wrappedCollect, l.finish
+}
+
+// Filter
+// the policy on rows read one by one in file order, from the first row after header.
+// fix returns the row fixed by the policy, false if it is dropped,
+// and the error of the error policy, after which no more rows should be read.
+// finish reports ragged rows as ParallelRead does.
+// rows are kept as they are on a nil Ragged
+func (r *Ragged) Filter(file string, header bool) (fix func(row string) (string, bool, error), finish func() error) {
+	if r == nil {
+		return func(row string) (string, bool, error) { return row, true, nil }, func() error { return nil }
+	}
+	l := r.newLog(file, header)
+	if l.err != nil {
+		return func(row string) (string, bool, error) { return row, false, l.err }, func() error { return l.err }
+	}
+	fix = func(row string) (string, bool, error) {
+		line := l.line
+		l.line += RecordLines(row)
+		fields := CountFields(row, r.Sep)
+		if fields == r.Fields {
+			return row, true, nil
+		}
+		l.add(line, row)
+		if l.err != nil {
+			return row, false, l.err
+		}
+		row, ok := r.Fix(row, fields, r.Fields)
+		return row, ok, nil
+	}
+	return fix, l.finish
+}
@@ -0,0 +1,129 @@
+package utility
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRaggedFix(t *testing.T) {
+	rows := []string{"1,2", "1,2,3,4", "1,\"x,y\",3,4"}
+	trueRows := map[string][]string{
+		RaggedPad:      {"1,2,", "1,2,3,4", "1,\"x,y\",3,4"},
+		RaggedTruncate: {"1,2", "1,2,3", "1,\"x,y\",3"},
+	}
+	for policy, fixed := range trueRows {
+		r, err := NewRagged(policy, "", ",")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range rows {
+			if v, ok := r.Fix(row, CountFields(row, ","), 3); !ok || v != fixed[i] {
+				t.Errorf("policy %s fixes row %s to %s, expected %s", policy, row, v, fixed[i])
+			}
+		}
+	}
+
+	r, _ := NewRagged(RaggedSkip, "", ",")
+	if _, ok := r.Fix("1,2", 2, 3); ok {
+		t.Error("policy skip should drop a ragged row.")
+	}
+	if _, err := NewRagged("drop", "", ","); err == nil {
+		t.Error("unknown policy should be an error.")
+	}
+}
+
+// file with a header, a quoted field over two lines, and ragged rows on lines 4 and 6
+const raggedContent = "a,b,c\n1,\"x\ny\",3\n4,5\n6,7,8\n9,10,11,12\n"
+
+func TestRaggedBadRowLines(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.csv")
+	if err := os.WriteFile(file, []byte(raggedContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	badRows := filepath.Join(dir, "bad.csv")
+	trueBad := "4,4,5\n6,9,10,11,12\n"
+
+	// parallel
+	r, _ := NewRagged(RaggedSkip, badRows, ",")
+	n := 0
+	release, err := ParallelRead(file, true, false, 1, r, func(rows []string) interface{} { return nil }, func(result interface{}, k int) { n += k })
+	release()
+	if err != nil || n != 2 || r.Count != 2 {
+		t.Errorf("parallel read kept %d rows and counted %d ragged rows, expected 2 and 2: %v", n, r.Count, err)
+	}
+	if b, _ := os.ReadFile(badRows); string(b) != trueBad {
+		t.Errorf("bad rows of parallel read are %q, expected %q", b, trueBad)
+	}
+
+	// one by one
+	r, _ = NewRagged(RaggedPad, badRows, ",")
+	fix, finish := r.Filter(file, true)
+	var rows []string
+	f, _ := os.Open(file)
+	defer f.Close()
+	br := NewRecordScanner(f)
+	br.Scan()
+	for br.Scan() {
+		if row, ok, err := fix(br.Text()); err == nil && ok {
+			rows = append(rows, row)
+		}
+	}
+	if err := finish(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rows, "|") != "1,\"x\ny\",3|4,5,|6,7,8|9,10,11,12" {
+		t.Errorf("filtered rows are %q", rows)
+	}
+	if b, _ := os.ReadFile(badRows); string(b) != trueBad {
+		t.Errorf("bad rows of filter are %q, expected %q", b, trueBad)
+	}
+
+	// error policy stops at the first ragged row, with its line
+	r, _ = NewRagged(RaggedError, "", ",")
+	_, err = ParallelRead(file, true, false, 1, r, func(rows []string) interface{} { return nil }, func(result interface{}, k int) {})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 4 ") {
+		t.Errorf("error policy error is %v, expected one at line 4", err)
+	}
+
+	// rows are kept by a nil policy
+	fix, _ = (*Ragged)(nil).Filter(file, true)
+	if row, ok, err := fix("1"); row != "1" || !ok || err != nil {
+		t.Error("nil policy should keep rows.")
+	}
+}
+
+func TestRaggedQuotedSeparator(t *testing.T) {
+	// a separator in a quoted field does not make a row ragged
+	file := filepath.Join(t.TempDir(), "a.csv")
+	if err := os.WriteFile(file, []byte("a,b\n1,\"x,y\"\n2,z\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, policy := range []string{RaggedError, RaggedSkip, RaggedPad, RaggedTruncate} {
+		r, _ := NewRagged(policy, "", ",")
+		n := 0
+		release, err := ParallelRead(file, true, false, 1, r, func(rows []string) interface{} { return nil }, func(result interface{}, k int) { n += k })
+		release()
+		if err != nil || n != 2 || r.Count != 0 {
+			t.Errorf("policy %s kept %d rows and counted %d ragged rows, expected 2 and 0: %v", policy, n, r.Count, err)
+		}
+
+		fix, finish := r.Filter(file, true)
+		if _, ok, err := fix("1,\"x,y\""); !ok || err != nil {
+			t.Errorf("policy %s drops a row with a quoted separator: %v", policy, err)
+		}
+		finish()
+	}
+}
+
+func TestRecordLines(t *testing.T) {
+	rows := []string{"", "a,b", "\"a\nb\",c", "\"a\r\nb\nc\""}
+	trueLines := []int{1, 1, 2, 3}
+	for i, row := range rows {
+		if RecordLines(row) != trueLines[i] {
+			t.Errorf("record %q has %d lines, expected %d", row, RecordLines(row), trueLines[i])
+		}
+	}
+}
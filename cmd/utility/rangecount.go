@@ -0,0 +1,115 @@
+package utility
+
+import (
+	"bytes"
+	"io"
+)
+
+// RangeCount
+// counts of a byte range of a file, read in one or more pieces.
+// ranges are counted independently, and merged in file order
+type RangeCount struct {
+	lines  int    // line terminators
+	quotes int    // quotes, in records mode
+	outer  [2]int // line terminators out of quotes, if the range starts out of or in quotes
+	last   byte   // last byte of the range
+	size   int    // bytes of the range
+	// quoted line terminators of records, if the range starts out of or in quotes:
+	// of the record continued from the previous range, of the record open at the end,
+	// and whether a record within the range has more than RecordMaxLines
+	head   [2]int
+	open   [2]int
+	ended  [2]bool // a record ends in the range
+	overly [2]bool
+}
+
+// Add
+// count the next piece of a range
+func (c *RangeCount) Add(b []byte, records bool) {
+	if len(b) == 0 {
+		return
+	}
+	if records {
+		for _, x := range b {
+			switch x {
+			case '"':
+				c.quotes++
+			case '\n':
+				c.outer[c.quotes%2]++
+				for s := 0; s < 2; s++ {
+					switch {
+					case (s+c.quotes)%2 == 0: // out of quotes, a record ends
+						if !c.ended[s] {
+							c.head[s] = c.open[s]
+						}
+						c.ended[s] = true
+						c.open[s] = 0
+					case c.ended[s]:
+						if c.open[s]++; c.open[s] > RecordMaxLines {
+							c.overly[s] = true
+						}
+					default:
+						c.open[s]++
+					}
+				}
+			}
+		}
+	} else {
+		c.lines += bytes.Count(b, []byte{'\n'})
+	}
+	c.last = b[len(b)-1]
+	c.size += len(b)
+}
+
+// MergeRangeCounts
+// lines, or quote-aware records, of ranges in file order.
+// a range starts in quotes if quotes before it are odd,
+// and a last line without line terminator is counted.
+// in records mode, stray is true if quotes are open at the end of file,
+// or a record has a quoted field over RecordMaxLines lines,
+// whose records are to be counted by a record scanner instead
+func MergeRangeCounts(counts []RangeCount, records bool) (n int, stray bool) {
+	inQuote := false
+	open := 0 // quoted line terminators of the record open at the end of ranges merged
+	last, size := byte(0), 0
+	for _, c := range counts {
+		if records {
+			s := 0
+			if inQuote {
+				s = 1
+			}
+			n += c.outer[s]
+			if c.ended[s] {
+				stray = stray || open+c.head[s] > RecordMaxLines || c.overly[s]
+				open = c.open[s]
+			} else {
+				open += c.open[s]
+			}
+			stray = stray || open > RecordMaxLines
+			if c.quotes%2 == 1 {
+				inQuote = !inQuote
+			}
+		} else {
+			n += c.lines
+		}
+		if c.size > 0 {
+			last = c.last
+			size += c.size
+		}
+	}
+	// last line without line terminator
+	if size > 0 && last != '\n' {
+		n++
+	}
+	return n, stray || inQuote
+}
+
+// CountRecords
+// records of r, read by a record scanner
+func CountRecords(r io.Reader) (n int, err error) {
+	br := NewRecordScanner(r)
+	for br.Scan() {
+		n++
+	}
+	return n, br.Err()
+}
@@ -0,0 +1,92 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRangeCount(t *testing.T) {
+	cases := []struct {
+		data    string
+		lines   int
+		records int
+		stray   bool
+	}{
+		{"a,b\n1,2\n", 2, 2, false},
+		{"a,b\n1,2", 2, 2, false},                    // no trailing line terminator
+		{"a,b\r\n1,\"x\r\ny\"\r\n2,z", 4, 3, false},  // quoted line terminator
+		{"a\n\"1\n2\n3\"\n4\n\"5\n6\"", 7, 4, false}, // quoted fields at the end, without line terminator
+		{"a,\"\"\"q\"\"\n\"\n", 2, 1, false},         // escaped quotes
+		{"", 0, 0, false},
+		{"a,b\n1,\"x\n2,y\n3,z\n", 4, 1, true},    // unbalanced quote
+		{"a,\"1\n2\",\"3\n4\"\n5\n", 4, 2, false}, // quoted fields of a record
+	}
+	for _, c := range cases {
+		// every way of cutting data into up to 3 ranges, each read in pieces of 2 bytes
+		for i := 0; i <= len(c.data); i++ {
+			for j := i; j <= len(c.data); j++ {
+				for _, records := range []bool{false, true} {
+					counts := make([]RangeCount, 3)
+					for k, r := range [][2]int{{0, i}, {i, j}, {j, len(c.data)}} {
+						for p := r[0]; p < r[1]; p += 2 {
+							end := p + 2
+							if end > r[1] {
+								end = r[1]
+							}
+							counts[k].Add([]byte(c.data[p:end]), records)
+						}
+					}
+					n, stray := MergeRangeCounts(counts, records)
+					expected := c.lines
+					if records {
+						expected = c.records
+					}
+					if n != expected || stray != (records && c.stray) {
+						t.Errorf("%q cut at %d and %d has %d (records %v, stray %v), expected %d", c.data, i, j, n, records, stray, expected)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRangeCountStray(t *testing.T) {
+	// quoted line terminators of a record over RecordMaxLines, in one or more fields, are stray
+	field := func(lines int) string {
+		return "\"" + strings.Repeat("x\n", lines) + "\""
+	}
+	for _, c := range []struct {
+		data  string
+		stray bool
+	}{
+		{"a\n" + field(RecordMaxLines) + "\nb\n", false},
+		{"a\n" + field(RecordMaxLines/2) + "\n" + field(RecordMaxLines/2+1) + "\nb\n", false},
+		{"a\n" + field(RecordMaxLines+1) + "\nb\n", true},
+		{"a\n" + field(RecordMaxLines/2) + "," + field(RecordMaxLines/2+1) + "\nb\n", true},
+	} {
+		for _, cut := range []int{1, 2, 3, 7} {
+			counts := make([]RangeCount, cut)
+			size := len(c.data)/cut + 1
+			for k := range counts {
+				start, end := k*size, (k+1)*size
+				if end > len(c.data) {
+					end = len(c.data)
+				}
+				if start < end {
+					counts[k].Add([]byte(c.data[start:end]), true)
+				}
+			}
+			if _, stray := MergeRangeCounts(counts, true); stray != c.stray {
+				t.Errorf("%d lines cut in %d ranges are stray: %v, expected %v", strings.Count(c.data, "\n"), cut, stray, c.stray)
+			}
+		}
+	}
+}
+
+func TestCountRecords(t *testing.T) {
+	// a stray quote ends its record at the line terminator, as in the index
+	n, err := CountRecords(strings.NewReader("a,b\n1,\"x\n2,y\n3,z\n"))
+	if err != nil || n != 4 {
+		t.Errorf("records are %d, expected 4", n)
+	}
+}
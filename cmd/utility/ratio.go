@@ -0,0 +1,112 @@
+package utility
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+)
+
+// RatioAssigner
+// rows are assigned to parts by seeded hash of row content, or of group value so that a group stays together,
+// so that a row keeps its part when rows are inserted or reordered, and equal rows share a part.
+// with stratify, a part is skipped when it is ahead of its share in the label,
+// so that label proportions are kept within about one row (or group) per part,
+// while the skipped rows depend on the order of rows in file.
+type RatioAssigner struct {
+	cum      []float64 // cumulative ratios
+	ratios   []float64
+	seed     int64
+	stratify int
+	group    int
+	counts   map[string][]int // rows or groups of each part, key is label
+	groups   map[string]int   // part of seen groups, used with stratify
+}
+
+// NewRatioAssigner
+// ratios sum up to 1, stratify and group are column indexes, -1 if not set
+func NewRatioAssigner(ratios []float64, seed int64, stratify int, group int) *RatioAssigner {
+	a := &RatioAssigner{ratios: ratios, seed: seed, stratify: stratify, group: group}
+	t := 0.0
+	for _, r := range ratios {
+		t += r
+		a.cum = append(a.cum, t)
+	}
+	a.counts = make(map[string][]int)
+	a.groups = make(map[string]int)
+	return a
+}
+
+// Assign
+// part of a data row
+func (a *RatioAssigner) Assign(fields []string) int {
+	id := strings.Join(fields, "\x00")
+	if a.group > -1 {
+		id = ""
+		if a.group < len(fields) {
+			id = fields[a.group]
+		}
+	}
+	if a.stratify < 0 {
+		return a.part(UnitHash(a.seed, id))
+	}
+
+	// stratified, group is decided by label of its first row
+	if a.group > -1 {
+		if j, ok := a.groups[id]; ok {
+			return j
+		}
+	}
+	label := ""
+	if a.stratify < len(fields) {
+		label = fields[a.stratify]
+	}
+	c, ok := a.counts[label]
+	if !ok {
+		c = make([]int, len(a.ratios))
+		a.counts[label] = c
+	}
+	n := 1
+	for _, v := range c {
+		n += v
+	}
+	j := a.part(UnitHash(a.seed, id))
+	if float64(c[j]) > a.ratios[j]*float64(n) {
+		// ahead of its share, choose the part most behind
+		best := math.Inf(-1)
+		for k, r := range a.ratios {
+			if d := r*float64(n) - float64(c[k]); d > best {
+				best, j = d, k
+			}
+		}
+	}
+	c[j]++
+	if a.group > -1 {
+		a.groups[id] = j
+	}
+	return j
+}
+
+// part of a uniform value in [0, 1)
+func (a *RatioAssigner) part(u float64) int {
+	for j, t := range a.cum {
+		if u < t {
+			return j
+		}
+	}
+	return len(a.cum) - 1
+}
+
+// UnitHash
+// uniform value in [0, 1) by fnv hash of key mixed with seed
+func UnitHash(seed int64, key string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64() ^ uint64(seed)*0x9e3779b97f4a7c15
+	// splitmix64 finalizer, spread bits of similar keys, e.g., row numbers
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
@@ -0,0 +1,123 @@
+package utility
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// rows of label (a 70%, b 30%) and group (5 rows each)
+func ratioRows(n int) (rows [][]string) {
+	for i := 0; i < n; i++ {
+		label := "a"
+		if i%10 >= 7 {
+			label = "b"
+		}
+		rows = append(rows, []string{strconv.Itoa(i), label, "g" + strconv.Itoa(i/5)})
+	}
+	return
+}
+
+func TestRatioAssignerProportions(t *testing.T) {
+	ratios := []float64{0.8, 0.1, 0.1}
+	rows := ratioRows(10000)
+
+	// hashed rows are close to ratios
+	a := NewRatioAssigner(ratios, 1, -1, -1)
+	counts := make([]int, len(ratios))
+	for _, row := range rows {
+		counts[a.Assign(row)]++
+	}
+	for j, r := range ratios {
+		if math.Abs(float64(counts[j])/float64(len(rows))-r) > 0.02 {
+			t.Errorf("part %d has %d rows, expected about %v", j, counts[j], r)
+		}
+	}
+
+	// stratified rows keep ratios within each label, within one row
+	a = NewRatioAssigner(ratios, 1, 1, -1)
+	labels := make(map[string][]int)
+	for _, row := range rows {
+		if labels[row[1]] == nil {
+			labels[row[1]] = make([]int, len(ratios))
+		}
+		labels[row[1]][a.Assign(row)]++
+	}
+	for label, c := range labels {
+		n := 0
+		for _, v := range c {
+			n += v
+		}
+		for j, r := range ratios {
+			if math.Abs(float64(c[j])-r*float64(n)) > 1 {
+				t.Errorf("part %d of label %s has %d of %d rows, expected %v", j, label, c[j], n, r*float64(n))
+			}
+		}
+	}
+}
+
+func TestRatioAssignerGroups(t *testing.T) {
+	ratios := []float64{0.5, 0.5}
+	rows := ratioRows(1000)
+	for _, stratify := range []int{-1, 1} {
+		a := NewRatioAssigner(ratios, 7, stratify, 2)
+		parts := make(map[string]int)
+		for _, row := range rows {
+			j := a.Assign(row)
+			if p, ok := parts[row[2]]; ok && p != j {
+				t.Errorf("group %s is split into parts %d and %d (stratify %d)", row[2], p, j, stratify)
+			}
+			parts[row[2]] = j
+		}
+	}
+}
+
+func TestRatioAssignerSeed(t *testing.T) {
+	ratios := []float64{0.5, 0.5}
+	rows := ratioRows(1000)
+	assign := func(seed int64) (r []int) {
+		a := NewRatioAssigner(ratios, seed, 1, -1)
+		for _, row := range rows {
+			r = append(r, a.Assign(row))
+		}
+		return
+	}
+	if !SliceIntEqual(assign(42), assign(42)) {
+		t.Error("assignment differs with the same seed.")
+	}
+	if SliceIntEqual(assign(42), assign(43)) {
+		t.Error("assignment is the same with different seeds.")
+	}
+
+	for i := 0; i < 1000; i++ {
+		if u := UnitHash(int64(i), strconv.Itoa(i)); u < 0 || u >= 1 {
+			t.Errorf("unit hash %v out of [0, 1)", u)
+		}
+	}
+}
+
+func TestRatioAssignerFileVersions(t *testing.T) {
+	ratios := []float64{0.8, 0.1, 0.1}
+	rows := ratioRows(1000)
+	// a later version of file, with rows inserted and in reverse order
+	var later [][]string
+	for i := len(rows) - 1; i >= 0; i-- {
+		later = append(later, rows[i])
+		if i%7 == 0 {
+			later = append(later, []string{"new" + strconv.Itoa(i), "a", "g_new"})
+		}
+	}
+	for _, group := range []int{-1, 2} {
+		parts := make(map[string]int)
+		a := NewRatioAssigner(ratios, 42, -1, group)
+		for _, row := range rows {
+			parts[row[0]] = a.Assign(row)
+		}
+		a = NewRatioAssigner(ratios, 42, -1, group)
+		for _, row := range later {
+			if p, ok := parts[row[0]]; ok && a.Assign(row) != p {
+				t.Fatalf("row %s changes part in a later file version (group %d)", row[0], group)
+			}
+		}
+	}
+}
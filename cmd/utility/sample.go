@@ -0,0 +1,32 @@
+package utility
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// SampleRecords
+// n distinct numbers in [0, total) in ascending order, all of them if n >= total.
+// Floyd's algorithm draws exactly n numbers, without a pass over [0, total)
+func SampleRecords(r *rand.Rand, total int, n int) []int {
+	if n > total {
+		n = total
+	}
+	if n <= 0 {
+		return nil
+	}
+	chosen := make(map[int]bool, n)
+	for j := total - n; j < total; j++ {
+		t := r.Intn(j + 1)
+		if chosen[t] {
+			t = j
+		}
+		chosen[t] = true
+	}
+	records := make([]int, 0, n)
+	for k := range chosen {
+		records = append(records, k)
+	}
+	sort.Ints(records)
+	return records
+}
@@ -0,0 +1,40 @@
+package utility
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSampleRecords(t *testing.T) {
+	for _, c := range []struct{ total, n, trueN int }{{100, 10, 10}, {10, 10, 10}, {5, 10, 5}, {0, 10, 0}, {-1, 10, 0}, {10, 0, 0}} {
+		records := SampleRecords(rand.New(rand.NewSource(1)), c.total, c.n)
+		if len(records) != c.trueN {
+			t.Errorf("%d of %d records are sampled, expected %d", len(records), c.total, c.trueN)
+		}
+		for i, k := range records {
+			if k < 0 || k >= c.total || i > 0 && k <= records[i-1] {
+				t.Errorf("sampled records %v should be distinct, ascending and in [0, %d)", records, c.total)
+				break
+			}
+		}
+	}
+
+	a := SampleRecords(rand.New(rand.NewSource(7)), 1000, 20)
+	if !SliceIntEqual(a, SampleRecords(rand.New(rand.NewSource(7)), 1000, 20)) {
+		t.Error("sample differs with the same seed.")
+	}
+
+	// each record is drawn with probability n/total
+	counts := make([]int, 10)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		for _, k := range SampleRecords(r, 10, 3) {
+			counts[k]++
+		}
+	}
+	for k, c := range counts {
+		if c < 2800 || c > 3200 {
+			t.Errorf("record %d is drawn %d times of 10000, expected about 3000", k, c)
+		}
+	}
+}
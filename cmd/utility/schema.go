@@ -0,0 +1,308 @@
+package utility
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const SchemaEnumMax = 20 // string columns with at most 20 distinct repeated values have enum candidates
+
+// rules of schema violations
+const (
+	RuleHeader  = "header"  // header names differ from schema
+	RuleColumns = "columns" // wrong number of fields
+	RuleType    = "type"    // value is not of column type
+	RuleNull    = "null"    // null value in a column not nullable
+	RuleRange   = "range"   // numeric value out of [min, max]
+	RuleEnum    = "enum"    // value not in enum
+	RulePattern = "pattern" // value does not match pattern
+)
+
+// rules in report order
+var SchemaRules = []string{RuleHeader, RuleColumns, RuleType, RuleNull, RuleRange, RuleEnum, RulePattern}
+
+// Schema
+// columns of a file, saved in json
+type Schema struct {
+	Header  bool           `json:"header"`
+	Sep     string         `json:"sep"`
+	Columns []SchemaColumn `json:"columns"`
+}
+
+// SchemaColumn
+// type is one of int, float, string and null (only null values are seen).
+// min and max are kept for numeric columns, enum or pattern for string columns
+type SchemaColumn struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Nullable bool     `json:"nullable"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+// Violation
+// row is the record number in file from 1, header included; col is the column index from 0
+type Violation struct {
+	Row    int
+	Col    int
+	Rule   string
+	Value  string
+	Detail string
+}
+
+// SchemaProfile
+// column profile of a batch of rows
+type SchemaProfile struct {
+	cType    int
+	nulls    int
+	valueN   int
+	numeric  bool // min and max are set
+	min      float64
+	max      float64
+	distinct map[string]bool // up to SchemaEnumMax+1 values
+	shapes   *Shapes
+}
+
+func NewSchemaProfiles(columnN int) []SchemaProfile {
+	profiles := make([]SchemaProfile, columnN)
+	for i := range profiles {
+		profiles[i] = SchemaProfile{cType: ColNull, distinct: make(map[string]bool), shapes: NewShapes()}
+	}
+	return profiles
+}
+
+// SchemaProcessRows
+// column profiles of a batch of rows, fields beyond columnN are ignored, missing fields are nulls
+func SchemaProcessRows(rows []string, sep string, columnN int) []SchemaProfile {
+	profiles := NewSchemaProfiles(columnN)
+	var fields []string // reused across rows
+	for _, row := range rows {
+		fields = SplitInto(row, sep, fields)
+		for i := range profiles {
+			p := &profiles[i]
+			if i >= len(fields) || IsNullValue(fields[i]) {
+				p.nulls++
+				continue
+			}
+			field := fields[i]
+			p.valueN++
+			p.cType = GuessFieldType(p.cType, field)
+			// infinities and nan are strings, and do not compare
+			if v, ok := ParseFinite(field); ok {
+				if !p.numeric || v < p.min {
+					p.min = v
+				}
+				if !p.numeric || v > p.max {
+					p.max = v
+				}
+				p.numeric = true
+			}
+			// values of a column may turn out to be strings in later rows
+			if len(p.distinct) <= SchemaEnumMax && !p.distinct[field] {
+				// a copy, not a substring keeping the row in memory
+				p.distinct[string(CopyBytes([]byte(field)))] = true
+			}
+			p.shapes.Add(field)
+		}
+	}
+	return profiles
+}
+
+// Merge
+// profile of a later batch, a column type widens from null to int, float and string
+func (p *SchemaProfile) Merge(o SchemaProfile) {
+	if p.cType == ColNull || (o.cType != ColNull && o.cType > p.cType) {
+		p.cType = o.cType
+	}
+	p.nulls += o.nulls
+	p.valueN += o.valueN
+	if o.numeric {
+		if !p.numeric || o.min < p.min {
+			p.min = o.min
+		}
+		if !p.numeric || o.max > p.max {
+			p.max = o.max
+		}
+		p.numeric = true
+	}
+	for v := range o.distinct {
+		if len(p.distinct) <= SchemaEnumMax {
+			p.distinct[v] = true
+		}
+	}
+	p.shapes.Merge(o.shapes)
+}
+
+// NewSchema
+// schema of columns of names and their profiles
+func NewSchema(names []string, header bool, sep string, profiles []SchemaProfile) *Schema {
+	s := &Schema{Header: header, Sep: sep}
+	for i, p := range profiles {
+		c := SchemaColumn{Name: names[i], Type: ColTypeName(p.cType), Nullable: p.nulls > 0}
+		switch p.cType {
+		case ColInt, ColFloat:
+			if p.numeric {
+				min, max := p.min, p.max
+				c.Min, c.Max = &min, &max
+			}
+		case ColString:
+			if len(p.distinct) <= SchemaEnumMax && p.valueN >= 2*len(p.distinct) {
+				for v := range p.distinct {
+					c.Enum = append(c.Enum, v)
+				}
+				sort.Strings(c.Enum)
+			} else {
+				c.Pattern = p.shapes.Pattern()
+			}
+		}
+		s.Columns = append(s.Columns, c)
+	}
+	return s
+}
+
+// LoadSchema
+// schema from a json file
+func LoadSchema(path string) (*Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Columns) == 0 {
+		return nil, errors.New("schema has no columns: " + path)
+	}
+	if s.Sep == "" {
+		s.Sep = ","
+	}
+	return &s, nil
+}
+
+// SchemaChecker
+// schema columns with parsed types, enum sets and compiled patterns
+type SchemaChecker struct {
+	schema   *Schema
+	types    []int
+	enums    []map[string]bool
+	patterns []*regexp.Regexp
+}
+
+func NewSchemaChecker(s *Schema) (*SchemaChecker, error) {
+	c := &SchemaChecker{schema: s}
+	for _, col := range s.Columns {
+		t := ColNull
+		switch col.Type {
+		case "int":
+			t = ColInt
+		case "float":
+			t = ColFloat
+		case "string":
+			t = ColString
+		case "null":
+		default:
+			return nil, errors.New("unknown type of column " + col.Name + ": " + col.Type)
+		}
+		c.types = append(c.types, t)
+
+		var enum map[string]bool
+		if len(col.Enum) > 0 {
+			enum = make(map[string]bool)
+			for _, v := range col.Enum {
+				enum[v] = true
+			}
+		}
+		c.enums = append(c.enums, enum)
+
+		var p *regexp.Regexp
+		if col.Pattern != "" {
+			var err error
+			if p, err = regexp.Compile(col.Pattern); err != nil {
+				return nil, errors.New("error pattern of column " + col.Name + ": " + col.Pattern)
+			}
+		}
+		c.patterns = append(c.patterns, p)
+	}
+	return c, nil
+}
+
+// CheckHeader
+// violations of header names, in row 1
+func (c *SchemaChecker) CheckHeader(names []string) (r []Violation) {
+	for i, col := range c.schema.Columns {
+		if i >= len(names) || names[i] != col.Name {
+			v := Violation{Row: 1, Col: i, Rule: RuleHeader, Detail: "expected " + strconv.Quote(col.Name)}
+			if i < len(names) {
+				v.Value = names[i]
+			}
+			r = append(r, v)
+		}
+	}
+	return
+}
+
+// Check
+// violations of a row, row is its index in a batch
+func (c *SchemaChecker) Check(fields []string, row int) (r []Violation) {
+	cols := c.schema.Columns
+	if len(fields) != len(cols) {
+		r = append(r, Violation{Row: row, Col: -1, Rule: RuleColumns,
+			Detail: "fields " + strconv.Itoa(len(fields)) + ", expected " + strconv.Itoa(len(cols))})
+	}
+	for i, col := range cols {
+		if i >= len(fields) {
+			break
+		}
+		field := fields[i]
+		if IsNullValue(field) {
+			if !col.Nullable {
+				r = append(r, Violation{Row: row, Col: i, Rule: RuleNull, Value: field, Detail: "not nullable"})
+			}
+			continue
+		}
+		switch c.types[i] {
+		case ColInt, ColFloat:
+			var v float64
+			ok := false
+			if c.types[i] == ColInt {
+				n, err := strconv.Atoi(field)
+				v, ok = float64(n), err == nil
+			} else {
+				v, ok = ParseFinite(field)
+			}
+			if !ok {
+				r = append(r, Violation{Row: row, Col: i, Rule: RuleType, Value: field, Detail: "expected " + col.Type})
+				continue
+			}
+			if (col.Min != nil && v < *col.Min) || (col.Max != nil && v > *col.Max) {
+				r = append(r, Violation{Row: row, Col: i, Rule: RuleRange, Value: field, Detail: "expected " + schemaRange(col)})
+			}
+		}
+		if c.enums[i] != nil && !c.enums[i][field] {
+			r = append(r, Violation{Row: row, Col: i, Rule: RuleEnum, Value: field, Detail: "expected one of " + strings.Join(col.Enum, ", ")})
+		}
+		if c.patterns[i] != nil && !c.patterns[i].MatchString(field) {
+			r = append(r, Violation{Row: row, Col: i, Rule: RulePattern, Value: field, Detail: "expected " + col.Pattern})
+		}
+	}
+	return
+}
+
+func schemaRange(col SchemaColumn) string {
+	min, max := "-inf", "inf"
+	if col.Min != nil {
+		min = strconv.FormatFloat(*col.Min, 'f', -1, 64)
+	}
+	if col.Max != nil {
+		max = strconv.FormatFloat(*col.Max, 'f', -1, 64)
+	}
+	return "[" + min + ", " + max + "]"
+}
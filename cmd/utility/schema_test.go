@@ -0,0 +1,147 @@
+package utility
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaRoundTrip(t *testing.T) {
+	names := []string{"id", "price", "city", "code", "note"}
+	batches := [][]string{
+		{"1,2.5,a,AB-01,", "2,-3,b,CD-22,x", "3,inf,a,EF-33,"},
+		{"4,NaN,b,GH-44,y", "5,10,a,IJ-55,", "6,,b,KL-66,z"},
+	}
+	profiles := NewSchemaProfiles(len(names))
+	for _, rows := range batches {
+		for i, p := range SchemaProcessRows(rows, ",", len(names)) {
+			profiles[i].Merge(p)
+		}
+	}
+	s := NewSchema(names, true, ",", profiles)
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		t.Fatalf("schema is not saved: %s", err.Error())
+	}
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = LoadSchema(path); err != nil {
+		t.Fatalf("schema is not loaded: %s", err.Error())
+	}
+
+	// inf and nan are strings, without min and max
+	trueTypes := []string{"int", "string", "string", "string", "string"}
+	for i, c := range s.Columns {
+		if c.Type != trueTypes[i] {
+			t.Errorf("column %s has type %s, expected %s", c.Name, c.Type, trueTypes[i])
+		}
+	}
+	if c := s.Columns[0]; c.Nullable || c.Min == nil || *c.Min != 1 || *c.Max != 6 {
+		t.Errorf("column id is %+v", c)
+	}
+	if c := s.Columns[2]; strings.Join(c.Enum, ",") != "a,b" {
+		t.Errorf("column city has enum %v", c.Enum)
+	}
+	if c := s.Columns[3]; c.Pattern != `^[A-Z]{2}-\d{2}$` {
+		t.Errorf("column code has pattern %s", c.Pattern)
+	}
+	if c := s.Columns[4]; !c.Nullable {
+		t.Error("column note should be nullable.")
+	}
+
+	// a file is valid against its own schema
+	checker, err := NewSchemaChecker(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := checker.CheckHeader(names); len(v) > 0 {
+		t.Errorf("header has violations %v", v)
+	}
+	var fields []string
+	for _, rows := range batches {
+		for i, row := range rows {
+			fields = SplitInto(row, ",", fields)
+			if v := checker.Check(fields, i); len(v) > 0 {
+				t.Errorf("row %s has violations %v", row, v)
+			}
+		}
+	}
+}
+
+func TestSchemaNonFinite(t *testing.T) {
+	// a float column with a later inf is a string, and its schema is saved
+	profiles := SchemaProcessRows([]string{"1.5", "+Inf", "-inf", "nan"}, ",", 1)
+	s := NewSchema([]string{"x"}, false, ",", profiles)
+	if c := s.Columns[0]; c.Type != "string" {
+		t.Errorf("column has type %s, expected string", c.Type)
+	}
+	if _, err := json.Marshal(s); err != nil {
+		t.Errorf("schema is not saved: %s", err.Error())
+	}
+
+	// min and max of a float column
+	profiles = SchemaProcessRows([]string{"1.5", "-2", "1e300"}, ",", 1)
+	if c := NewSchema([]string{"x"}, false, ",", profiles).Columns[0]; c.Type != "float" || *c.Min != -2 || *c.Max != 1e300 {
+		t.Errorf("column is %+v", c)
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	min, max := 0.0, 10.0
+	s := &Schema{Header: true, Sep: ",", Columns: []SchemaColumn{
+		{Name: "n", Type: "int", Min: &min, Max: &max},
+		{Name: "f", Type: "float", Nullable: true},
+		{Name: "e", Type: "string", Enum: []string{"a", "b"}},
+		{Name: "p", Type: "string", Pattern: `^\d+$`},
+	}}
+	checker, err := NewSchemaChecker(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := checker.CheckHeader([]string{"n", "g", "e"}); len(v) != 2 || v[0].Rule != RuleHeader || v[0].Col != 1 || v[0].Value != "g" || v[1].Col != 3 {
+		t.Errorf("header violations are %v", v)
+	}
+
+	cases := []struct {
+		row  string
+		rule string
+		col  int
+	}{
+		{"1,1.5,a,12", "", 0},
+		{"1,,a,12", "", 0},
+		{"1,1.5,a", RuleColumns, -1},
+		{"1,1.5,a,12,x", RuleColumns, -1},
+		{"1.5,1.5,a,12", RuleType, 0},
+		{"1,inf,a,12", RuleType, 1},
+		{",1.5,a,12", RuleNull, 0},
+		{"11,1.5,a,12", RuleRange, 0},
+		{"-1,1.5,a,12", RuleRange, 0},
+		{"1,1.5,c,12", RuleEnum, 2},
+		{"1,1.5,a,x1", RulePattern, 3},
+	}
+	var fields []string
+	for i, c := range cases {
+		fields = SplitInto(c.row, ",", fields)
+		v := checker.Check(fields, i)
+		if c.rule == "" {
+			if len(v) > 0 {
+				t.Errorf("row %s has violations %v", c.row, v)
+			}
+			continue
+		}
+		if len(v) != 1 || v[0].Rule != c.rule || v[0].Col != c.col || v[0].Row != i {
+			t.Errorf("row %s has violations %v, expected %s of column %d", c.row, v, c.rule, c.col)
+		}
+	}
+
+	if _, err := NewSchemaChecker(&Schema{Columns: []SchemaColumn{{Name: "x", Type: "date"}}}); err == nil {
+		t.Error("a column of unknown type should be an error.")
+	}
+	if _, err := NewSchemaChecker(&Schema{Columns: []SchemaColumn{{Name: "x", Type: "string", Pattern: "("}}}); err == nil {
+		t.Error("a column of error pattern should be an error.")
+	}
+}
@@ -0,0 +1,209 @@
+package utility
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+const (
+	SketchEpsilon    = 0.0001 // overcount of count-min sketch, as a fraction of total count
+	SketchDelta      = 0.01   // probability of an overcount over SketchEpsilon
+	SketchEntryBytes = 96     // approximate bytes of a space-saving entry, excluding the value
+)
+
+// CountMinSketch
+// approximate counts in fixed memory,
+// estimates never undercount, and overcount at most e/width*N with probability 1-e^-depth
+type CountMinSketch struct {
+	width int
+	depth int
+	table [][]int
+	N     int // total count added
+}
+
+func NewCountMinSketch(width, depth int) *CountMinSketch {
+	s := &CountMinSketch{width: width, depth: depth}
+	for i := 0; i < depth; i++ {
+		s.table = append(s.table, make([]int, width))
+	}
+	return s
+}
+
+// cell indexes of a value, from two hashes (Kirsch-Mitzenmacher)
+func (s *CountMinSketch) index(i int, h1, h2 uint32) int {
+	return int((h1 + uint32(i)*h2) % uint32(s.width))
+}
+
+func hashes(v string) (uint32, uint32) {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	sum := h.Sum64()
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+func (s *CountMinSketch) Add(v string, count int) {
+	h1, h2 := hashes(v)
+	for i := 0; i < s.depth; i++ {
+		s.table[i][s.index(i, h1, h2)] += count
+	}
+	s.N += count
+}
+
+func (s *CountMinSketch) Estimate(v string) int {
+	h1, h2 := hashes(v)
+	r := math.MaxInt64
+	for i := 0; i < s.depth; i++ {
+		if c := s.table[i][s.index(i, h1, h2)]; c < r {
+			r = c
+		}
+	}
+	return r
+}
+
+// ErrorBound
+// maximum overcount of an estimate, with probability 1-e^-depth
+func (s *CountMinSketch) ErrorBound() int {
+	return int(math.Ceil(math.E / float64(s.width) * float64(s.N)))
+}
+
+// SpaceSaving
+// heavy hitters in fixed memory, keeps at most k values.
+// a new value replaces the least counted one and inherits its count as error,
+// so that count-error <= true count <= count.
+type SpaceSaving struct {
+	k     int
+	items map[string]*SpaceSavingItem
+	h     ssHeap
+}
+
+type SpaceSavingItem struct {
+	Value string
+	Count int
+	Err   int
+	index int
+}
+
+func NewSpaceSaving(k int) *SpaceSaving {
+	return &SpaceSaving{k: k, items: make(map[string]*SpaceSavingItem)}
+}
+
+func (s *SpaceSaving) Add(v string, count int) {
+	if it, ok := s.items[v]; ok {
+		it.Count += count
+		heap.Fix(&s.h, it.index)
+		return
+	}
+	if len(s.items) < s.k {
+		it := &SpaceSavingItem{Value: v, Count: count}
+		s.items[v] = it
+		heap.Push(&s.h, it)
+		return
+	}
+	// replace the least counted value
+	it := s.h[0]
+	delete(s.items, it.Value)
+	it.Value, it.Err, it.Count = v, it.Count, it.Count+count
+	s.items[v] = it
+	heap.Fix(&s.h, 0)
+}
+
+// Items
+// kept values in descending order of count
+func (s *SpaceSaving) Items() []SpaceSavingItem {
+	var r []SpaceSavingItem
+	for _, it := range s.items {
+		r = append(r, *it)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Count == r[j].Count {
+			return r[i].Value < r[j].Value
+		}
+		return r[i].Count > r[j].Count
+	})
+	return r
+}
+
+// min-heap on count
+type ssHeap []*SpaceSavingItem
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ssHeap) Push(x interface{}) {
+	it := x.(*SpaceSavingItem)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *ssHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+// FreqSketch
+// approximate frequency table within a memory budget:
+// space-saving finds heavy hitters, count-min sketch tightens their counts
+type FreqSketch struct {
+	cms *CountMinSketch
+	ss  *SpaceSaving
+}
+
+// NewFreqSketch
+// count-min sketch is sized by SketchEpsilon and SketchDelta, capped by half of the memory budget.
+// the other half caps the values kept by space-saving, whose entries are allocated as values arrive
+func NewFreqSketch(memoryBytes int) *FreqSketch {
+	depth := int(math.Ceil(math.Log(1 / SketchDelta)))
+	width := int(math.Ceil(math.E / SketchEpsilon))
+	if w := memoryBytes / 2 / 8 / depth; w < width {
+		width = w
+	}
+	k := memoryBytes / 2 / SketchEntryBytes
+	if width < 64 {
+		width = 64
+	}
+	if k < 16 {
+		k = 16
+	}
+	return &FreqSketch{NewCountMinSketch(width, depth), NewSpaceSaving(k)}
+}
+
+func (s *FreqSketch) Add(v string, count int) {
+	s.cms.Add(v, count)
+	s.ss.Add(v, count)
+}
+
+// Total count added
+func (s *FreqSketch) Total() int {
+	return s.cms.N
+}
+
+// ErrorBound
+// maximum overcount of count-min sketch, with probability 1-SketchDelta
+func (s *FreqSketch) ErrorBound() int {
+	return s.cms.ErrorBound()
+}
+
+// Estimates
+// estimated counts of heavy hitters, and the maximum overcount of each estimate.
+// both structures overcount, the smaller one is kept.
+func (s *FreqSketch) Estimates() (counts map[string]int, errs map[string]int) {
+	counts, errs = make(map[string]int), make(map[string]int)
+	for _, it := range s.ss.Items() {
+		c := it.Count
+		if e := s.cms.Estimate(it.Value); e < c {
+			c = e
+		}
+		counts[it.Value] = c
+		errs[it.Value] = c - (it.Count - it.Err) // space-saving lower bound
+	}
+	return
+}
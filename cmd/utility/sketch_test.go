@@ -0,0 +1,52 @@
+package utility
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFreqSketch(t *testing.T) {
+	s := NewFreqSketch(64 * 1024)
+	truth := make(map[string]int)
+	// heavy hitters a, b and c, with a long tail of unique values
+	for i := 0; i < 100000; i++ {
+		v := strconv.Itoa(i)
+		switch i % 10 {
+		case 0, 1, 2:
+			v = "a"
+		case 3, 4:
+			v = "b"
+		case 5:
+			v = "c"
+		}
+		s.Add(v, 1)
+		truth[v]++
+	}
+
+	if s.Total() != 100000 {
+		t.Error("sketch total count error.")
+	}
+	counts, errs := s.Estimates()
+	for _, v := range []string{"a", "b", "c"} {
+		c, ok := counts[v]
+		if !ok {
+			t.Fatalf("heavy hitter %s is missing.", v)
+		}
+		if c < truth[v] || c-errs[v] > truth[v] {
+			t.Errorf("true count of %s is %d, out of estimated range [%d, %d].", v, truth[v], c-errs[v], c)
+		}
+	}
+}
+
+func TestFreqSketchSize(t *testing.T) {
+	// a large budget does not allocate beyond the error target
+	s := NewFreqSketch(1024 * 1024 * 1024)
+	if s.cms.depth != 5 || s.cms.width != 27183 || len(s.ss.items) != 0 {
+		t.Errorf("sketch of 1GB has width %d, depth %d and %d values", s.cms.width, s.cms.depth, len(s.ss.items))
+	}
+	// a small budget caps the width
+	s = NewFreqSketch(64 * 1024)
+	if s.cms.width != 64*1024/2/8/5 {
+		t.Errorf("sketch of 64KB has width %d", s.cms.width)
+	}
+}
@@ -0,0 +1,160 @@
+package utility
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// RenderTable
+// rows in a table with header names, cells are truncated to width.
+// rows are numbered from first, rows of unequal length are padded.
+// without names, columns are named by position
+func RenderTable(w io.Writer, names []string, rows [][]string, first int, width int) {
+	columnN := ColumnCount(names, rows)
+	header := []string{"#"}
+	for i := 0; i < columnN; i++ {
+		if i < len(names) {
+			header = append(header, TruncateCell(names[i], width))
+		} else {
+			header = append(header, "col_"+strconv.Itoa(i+1))
+		}
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	for i, row := range rows {
+		cells := []string{strconv.Itoa(first + i)}
+		for j := 0; j < columnN; j++ {
+			if j < len(row) {
+				cells = append(cells, TruncateCell(row[j], width))
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		table.Append(cells)
+	}
+	table.Render()
+}
+
+// TruncateCell
+// a value in one line of at most width runes, line breaks are shown as spaces,
+// a truncated value ends with "…"
+func TruncateCell(v string, width int) string {
+	v = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(v)
+	if width <= 0 {
+		return v
+	}
+	runes := []rune(v)
+	if len(runes) <= width {
+		return v
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// ColumnCount
+// columns of a table, the most of names and fields of rows
+func ColumnCount(names []string, rows [][]string) int {
+	n := len(names)
+	for _, row := range rows {
+		if len(row) > n {
+			n = len(row)
+		}
+	}
+	return n
+}
+
+// FitColumns
+// frozen columns, and columns from frozen+left which fit the screen,
+// in a table of rows numbered from first as by RenderTable.
+// at least one scrolled column is shown
+func FitColumns(names []string, rows [][]string, first int, left int, frozen int, width int, screen int) (cols []int) {
+	columnN := ColumnCount(names, rows)
+	used := len(strconv.Itoa(first+len(rows))) + 7 // row number column and borders
+	add := func(c int) bool {
+		w := 0
+		if c < len(names) {
+			w = len([]rune(TruncateCell(names[c], width)))
+		}
+		for _, row := range rows {
+			if c < len(row) {
+				if l := len([]rune(TruncateCell(row[c], width))); l > w {
+					w = l
+				}
+			}
+		}
+		if used+w+3 > screen && len(cols) > frozen {
+			return false
+		}
+		used += w + 3
+		cols = append(cols, c)
+		return true
+	}
+	for c := 0; c < frozen && c < columnN; c++ {
+		add(c)
+	}
+	for c := frozen + left; c < columnN; c++ {
+		if !add(c) {
+			break
+		}
+	}
+	return
+}
+
+// PickColumns
+// names of columns, columns without a name are named by position
+func PickColumns(names []string, cols []int) (r []string) {
+	for _, c := range cols {
+		if c < len(names) {
+			r = append(r, names[c])
+		} else {
+			r = append(r, "col_"+strconv.Itoa(c+1))
+		}
+	}
+	return
+}
+
+// PickRows
+// fields of columns, missing fields are empty
+func PickRows(rows [][]string, cols []int) (r [][]string) {
+	for _, row := range rows {
+		var picked []string
+		for _, c := range cols {
+			if c < len(row) {
+				picked = append(picked, row[c])
+			} else {
+				picked = append(picked, "")
+			}
+		}
+		r = append(r, picked)
+	}
+	return
+}
+
+// ColumnRange
+// shown columns as ranges, e.g., 0,3-5
+func ColumnRange(cols []int) string {
+	if len(cols) == 0 {
+		return "-"
+	}
+	var ranges []string
+	start := 0
+	for i := 1; i <= len(cols); i++ {
+		if i == len(cols) || cols[i] != cols[i-1]+1 {
+			if i-1 == start {
+				ranges = append(ranges, strconv.Itoa(cols[start]))
+			} else {
+				ranges = append(ranges, strconv.Itoa(cols[start])+"-"+strconv.Itoa(cols[i-1]))
+			}
+			start = i
+		}
+	}
+	return strings.Join(ranges, ",")
+}
@@ -0,0 +1,84 @@
+package utility
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTruncateCell(t *testing.T) {
+	values := []string{"abc", "abcdef", "a\r\nb\nc", "中文字段值", "abc", "abc"}
+	widths := []int{5, 4, 10, 3, 1, 0}
+	trueValues := []string{"abc", "abc…", "a b c", "中文…", "…", "abc"}
+	for i, v := range values {
+		if r := TruncateCell(v, widths[i]); r != trueValues[i] {
+			t.Errorf("cell %q truncated to %d is %q, expected %q", v, widths[i], r, trueValues[i])
+		}
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	var buf bytes.Buffer
+	RenderTable(&buf, []string{"id"}, [][]string{{"1", "abcdef"}, {"2"}}, 10, 4)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	trueLines := []string{
+		"+----+----+-------+",
+		"| #  | id | col_2 |",
+		"+----+----+-------+",
+		"| 10 |  1 | abc…  |",
+		"| 11 |  2 |       |",
+		"+----+----+-------+",
+	}
+	if strings.Join(lines, "\n") != strings.Join(trueLines, "\n") {
+		t.Errorf("table is\n%s\nexpected\n%s", strings.Join(lines, "\n"), strings.Join(trueLines, "\n"))
+	}
+}
+
+func TestFitColumns(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	rows := [][]string{{"1234", "1234", "1234", "1234"}, {"1"}}
+
+	// row number column of 1 digit and borders take 8, each column 7, or 5 truncated to 2
+	if r := FitColumns(names, rows, 0, 0, 0, 0, 100); !SliceIntEqual(r, []int{0, 1, 2, 3}) {
+		t.Errorf("all columns fit, got %v", r)
+	}
+	if r := FitColumns(names, rows, 0, 0, 0, 0, 22); !SliceIntEqual(r, []int{0, 1}) {
+		t.Errorf("two columns fit, got %v", r)
+	}
+	if r := FitColumns(names, rows, 0, 0, 0, 2, 23); !SliceIntEqual(r, []int{0, 1, 2}) {
+		t.Errorf("three truncated columns fit, got %v", r)
+	}
+
+	// frozen columns are kept while scrolling, and at least one scrolled column is shown
+	if r := FitColumns(names, rows, 0, 1, 1, 0, 22); !SliceIntEqual(r, []int{0, 2}) {
+		t.Errorf("scrolled with one frozen column, got %v", r)
+	}
+	if r := FitColumns(names, rows, 0, 2, 1, 0, 10); !SliceIntEqual(r, []int{0, 3}) {
+		t.Errorf("narrow screen shows one scrolled column, got %v", r)
+	}
+
+	// wider row numbers take more of the screen
+	if r := FitColumns(names, rows, 99999, 0, 0, 0, 22); !SliceIntEqual(r, []int{0}) {
+		t.Errorf("one column fits with 6-digit row numbers, got %v", r)
+	}
+}
+
+func TestPickColumns(t *testing.T) {
+	if r := PickColumns([]string{"a", "b"}, []int{1, 3}); strings.Join(r, ",") != "b,col_4" {
+		t.Errorf("picked names are %v", r)
+	}
+	r := PickRows([][]string{{"1", "2"}, {"3", "4", "5", "6"}}, []int{1, 3})
+	if len(r) != 2 || strings.Join(r[0], ",") != "2," || strings.Join(r[1], ",") != "4,6" {
+		t.Errorf("picked rows are %v", r)
+	}
+}
+
+func TestColumnRange(t *testing.T) {
+	cols := [][]int{nil, {0}, {0, 1, 2}, {0, 3, 4, 5, 7}}
+	trueRanges := []string{"-", "0", "0-2", "0,3-5,7"}
+	for i, c := range cols {
+		if r := ColumnRange(c); r != trueRanges[i] {
+			t.Errorf("range of %v is %q, expected %q", c, r, trueRanges[i])
+		}
+	}
+}
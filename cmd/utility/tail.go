@@ -0,0 +1,49 @@
+package utility
+
+import (
+	"io"
+)
+
+// TailOffset
+// offset of the first of the last n records of r of size bytes, read backwards from the end.
+// a line terminator ends a record if the number of quotes after it is even,
+// which holds for well-formed files whose last record is not in quotes.
+// unlike reading forwards, a stray quote is not known to be one,
+// so the records after it are taken by parity and may be merged or split
+func TailOffset(r io.ReaderAt, size int64, n int) (int64, error) {
+	if n == 0 {
+		return size, nil
+	}
+	buf := make([]byte, 64*1024)
+	quotes, found := 0, 0
+	end := size
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		b := buf[:end-start]
+		if _, err := r.ReadAt(b, start); err != nil {
+			return 0, err
+		}
+		for i := len(b) - 1; i >= 0; i-- {
+			switch b[i] {
+			case '"':
+				quotes++
+			case '\n':
+				// line terminator of the last record
+				if start+int64(i) == size-1 {
+					continue
+				}
+				if quotes%2 == 0 {
+					found++
+					if found == n {
+						return start + int64(i) + 1, nil
+					}
+				}
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,39 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTailOffset(t *testing.T) {
+	files := []string{
+		"a,b\n1,x\n2,\"y\nz\"\n",         // quoted multi-line last record
+		"a,b\n1,x\n2,y",                  // no trailing newline
+		"a,b\r\n1,x\r\n2,\"y\r\nz\"\r\n", // CRLF
+		"a,b\n1,x\n",                     // n larger than the number of records
+		"",
+	}
+	ns := []int{1, 1, 2, 5, 3}
+	trueTails := []string{"2,\"y\nz\"\n", "2,y", "1,x\r\n2,\"y\r\nz\"\r\n", "a,b\n1,x\n", ""}
+	for i, content := range files {
+		offset, err := TailOffset(strings.NewReader(content), int64(len(content)), ns[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tail := content[offset:]; tail != trueTails[i] {
+			t.Errorf("last %d records of %q are %q, expected %q", ns[i], content, tail, trueTails[i])
+		}
+	}
+
+	// no record is shown for n 0
+	if offset, _ := TailOffset(strings.NewReader("a\nb\n"), 4, 0); offset != 4 {
+		t.Errorf("offset of last 0 records is %d, expected 4", offset)
+	}
+
+	// a long file is read in several blocks
+	content := strings.Repeat("1,\"x\ny\"\n", 20000)
+	offset, _ := TailOffset(strings.NewReader(content), int64(len(content)), 10000)
+	if offset != int64(len(content)/2) {
+		t.Errorf("offset of last 10000 records is %d, expected %d", offset, len(content)/2)
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ribbondz/gsv/cmd/utility"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ViewPageRows    = 20   // default records of a page
+	ViewScreenWidth = 160  // default screen width, if $COLUMNS is not set
+	ViewStep        = 1000 // records between two offsets kept while reading
+)
+
+const viewHelp = `commands:
+ n, enter   next page
+ p          previous page
+ g N        jump to record N
+ r / l      scroll one column right / left
+ f N        freeze first N columns
+ q          quit`
+
+// viewReader
+// records of a file by record number, header included.
+// records are seeked by a fresh index, or by an index of offsets kept as the file is read,
+// so that jumping back does not read from the beginning
+type viewReader struct {
+	f       *os.File
+	idx     *utility.Index
+	records int // total records, -1 if not read to the end
+}
+
+func newViewReader(f *os.File) *viewReader {
+	if idx, fresh := utility.LoadIndex(f.Name()); fresh && len(idx.Offsets) > 0 {
+		return &viewReader{f: f, idx: idx, records: idx.Records}
+	}
+	return &viewReader{f: f, idx: &utility.Index{Step: ViewStep, Offsets: []int64{0}}, records: -1}
+}
+
+// page
+// records [start, start+n), fewer at the end of file
+func (v *viewReader) page(start int, n int) ([]string, error) {
+	offset, skip := v.idx.Seek(start)
+	record := start - skip
+	if _, err := v.f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	br := utility.NewRecordScanner(v.f)
+	br.Split(utility.ScanRawRecords) // lengths of records with line terminators are kept as offsets
+	var rows []string
+	for record < start+n {
+		if !br.Scan() {
+			if err := br.Err(); err != nil {
+				return nil, err
+			}
+			v.records = record
+			break
+		}
+		raw := br.Text()
+		if record >= start {
+			rows = append(rows, strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r"))
+		}
+		offset += int64(len(raw))
+		record++
+		if record%v.idx.Step == 0 && record/v.idx.Step == len(v.idx.Offsets) {
+			v.idx.Offsets = append(v.idx.Offsets, offset)
+		}
+	}
+	return rows, nil
+}
+
+// View
+// a pager of records in a table, commands are read from stdin line by line.
+// columns which do not fit the screen are scrolled, first columns can be frozen
+func View(file string, header bool, sep string, pageRows int, width int) {
+	// check file existence
+	if !utility.FileIsExist(file) {
+		fmt.Print("File does not exist. Try command 'gsv view --help'.")
+		return
+	}
+	if pageRows <= 0 {
+		pageRows = ViewPageRows
+	}
+
+	f, err := os.Open(file)
+	utility.CheckErr(err)
+	defer f.Close()
+
+	v := newViewReader(f)
+	base := 0 // records before the first shown record, i.e., the header
+	var names []string
+	if header {
+		r, err := v.page(0, 1)
+		utility.CheckErr(err)
+		if len(r) > 0 {
+			names = strings.Split(r[0], sep)
+		}
+		base = 1
+	}
+	screen := viewScreenWidth()
+
+	top, left, frozen := 0, 0, 0 // first record, scrolled columns and frozen columns
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		lines, err := v.page(base+top, pageRows)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		if len(lines) == 0 && top == 0 {
+			fmt.Print("No records in file.")
+			return
+		}
+		if len(lines) == 0 {
+			// jumped out of file, show the last page
+			top = v.records - base - pageRows
+			if top < 0 {
+				top = 0
+			}
+			continue
+		}
+		var rows [][]string
+		for _, line := range lines {
+			rows = append(rows, strings.Split(line, sep))
+		}
+		pageNames := names
+		if !header {
+			for i := len(pageNames); i < utility.ColumnCount(nil, rows); i++ {
+				pageNames = append(pageNames, "col_"+strconv.Itoa(i+1))
+			}
+		}
+
+		cols := utility.FitColumns(pageNames, rows, top, left, frozen, width, screen)
+		utility.RenderTable(os.Stdout, utility.PickColumns(pageNames, cols), utility.PickRows(rows, cols), top, width)
+		total := "?"
+		if v.records >= 0 {
+			total = strconv.Itoa(v.records - base)
+		}
+		fmt.Printf("records %d-%d of %s, columns %s, frozen %d (n/p/g N/r/l/f N/q, ? for help): ",
+			top, top+len(rows)-1, total, utility.ColumnRange(cols), frozen)
+
+		if !in.Scan() {
+			fmt.Println()
+			return
+		}
+		cmd := strings.Fields(in.Text())
+		arg := -1
+		if len(cmd) > 1 {
+			if n, err := strconv.Atoi(cmd[1]); err == nil && n >= 0 {
+				arg = n
+			}
+		}
+		if len(cmd) == 0 {
+			cmd = []string{"n"}
+		}
+		switch cmd[0] {
+		case "n":
+			if len(rows) == pageRows {
+				top += pageRows
+			}
+		case "p":
+			top -= pageRows
+			if top < 0 {
+				top = 0
+			}
+		case "g":
+			if arg >= 0 {
+				top = arg
+			}
+		case "r":
+			if len(cols) > 0 && cols[len(cols)-1] < utility.ColumnCount(pageNames, rows)-1 {
+				left++
+			}
+		case "l":
+			if left > 0 {
+				left--
+			}
+		case "f":
+			if arg >= 0 {
+				frozen = arg
+			}
+		case "q":
+			return
+		default:
+			fmt.Println(viewHelp)
+		}
+	}
+}
+
+// viewScreenWidth
+// terminal width from $COLUMNS
+func viewScreenWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return ViewScreenWidth
+}
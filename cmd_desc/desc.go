@@ -2,18 +2,137 @@ package cmd_desc
 
 const (
 	Head = `examples:
-	 gsv head a.txt         // head 20 rows (default)
-	 gsv head -l 50 a.txt   // head 50 rows
+	 gsv head a.txt              // head 20 rows (default)
+	 gsv head -l 50 a.txt        // head 50 rows
+	 gsv head -t a.txt           // header and 20 records in a table, cells truncated to 20 characters
+	 gsv head -t -w 40 a.txt     // cells truncated to 40 characters
+	 gsv head -t -s \t a.txt     // separator tab
+	 gsv head -t -n a.txt        // no header, columns are named by position
+
+	 with a fresh index (see gsv index), the table is followed by the total of records.
+`
+
+	Lint = `examples:
+	 gsv lint a.txt           // check a.txt for common problems
+	 gsv lint -s \t a.txt     // separator tab
+
+	 checks, each reported with its count and first line numbers:
+	 inconsistent field counts, fields of a row differ from the first row
+	 mixed line endings, lines of endings (CRLF, LF or CR) other than the most common one
+	 byte order mark at the start of a line
+	 invalid utf-8
+	 unbalanced quotes, or quotes in a field which is not well-formed quoted
+	 leading or trailing whitespace in values
+	 empty or duplicate header names
+	 thousand separators in numbers, e.g., 1,234
+	 duplicate rows, rows equal to an earlier row, compared with the first 256MB of distinct rows
+
+	 fields are split out of quotes. the header is checked for the format and names, not as a data row.
+	 the exit status is 1 if there is any problem.
+`
+
+	Schema = `examples:
+	 gsv schema infer a.txt > schema.json        // infer schema of a.txt, saved in schema.json
+	 gsv schema infer -n -s \t a.txt > s.json    // no header, separator tab
+
+	 schema fields of each column:
+	 name, type (int, float, string or null; inf and nan are strings), nullable,
+	 min and max of numeric columns,
+	 enum of string columns with at most 20 distinct repeated values,
+	 otherwise pattern of string columns, a regular expression of the common shape of values, e.g., ^[A-Z]{2}-\d{4}$
+
+	 the schema can be edited before validation, see 'gsv validate --help'.
+`
+
+	Validate = `examples:
+	 gsv validate --schema schema.json a.txt     // validate a.txt against schema.json
+
+	 each violation is reported with row (record number from 1, header included) and column, e.g.,
+	 row 12, column 2 (price): range, value "-5", expected [0, 100]
+
+	 rules: header, columns (wrong number of fields), type, null, range, enum and pattern.
+	 the exit status is 1 if there is any violation.
+`
+
+	View = `examples:
+	 gsv view a.txt              // 20 records a page (default)
+	 gsv view -l 50 -w 40 a.txt  // 50 records a page, cells truncated to 40 characters
+
+	 commands typed at the prompt, followed by enter:
+	 n, enter   next page
+	 p          previous page
+	 g N        jump to record N
+	 r / l      scroll one column right / left
+	 f N        freeze first N columns
+	 q          quit
+
+	 columns fitting the screen width $COLUMNS (default 160) are shown.
+	 a fresh index built by 'gsv index' is used to jump to records.
 `
 
 	Header = `examples:
-	 gsv header a.txt         // separator "," (default)
-	 gsv header -s \t a.txt   // separator tab
+	 gsv header a.txt              // separator "," (default)
+	 gsv header -s \t a.txt        // separator tab
+	 gsv header -n a.txt           // no header, columns are named by position
+	 gsv header -r 10000 a.txt     // types, null rates and samples from first 10000 rows, 1000 by default
+	 gsv header --samples 10 a.txt // 10 distinct sample values of each column, 5 by default
+
+	 output fields:
+	 #, header, type, null, samples
+	 0, id,     int,  0.00%, 1, 2, 3
+
+	 rows of unequal length are counted, missing fields are nulls,
+	 and columns beyond the header are named by position.
 `
 
 	Count = `examples:
 	 gsv count a.txt
+	 gsv count -r a.txt         // quote-aware records, a line break in a quoted field does not end a record
+	 gsv count a.txt b.txt      // a table of counts of each file, and the total
+	 gsv count --mmap a.txt     // count by memory mapping
+	 gsv count -f 1=failed a.txt            // records with second column "failed", same filter syntax as select
+	 gsv count -b status a.txt              // counts and percentages of records by values of column status
+	 gsv count -b region -f 1=failed a.txt  // filtered records by values of column region
 	 gsv count --help           // help info 
+
+	 byte ranges of a file are counted in parallel, a last line without line break is counted.
+	 the count is read from a fresh index built by 'gsv index' if there is one
+`
+
+	Index = `examples:
+	 gsv index a.txt              // write index a.txt.gsvi, an offset every 10000 records (default)
+	 gsv index --step 1000 a.txt  // an offset every 1000 records
+
+	 the index keeps quote-aware record offsets, and size and modification time of the file.
+	 count, head, slice, sample and commands reading in parallel use the index
+	 if the file is not changed after indexing.
+`
+
+	Slice = `examples:
+	 gsv slice --start 1000 --end 1020 a.txt   // header, and records 1000 to 1019 counted from 0 after the header
+	 gsv slice --start 1000 -l 20 a.txt        // same as above
+	 gsv slice --end 5 a.txt                   // header and first 5 records
+	 gsv slice -n --start 1000 a.txt           // no header, records 1000 to 1019 counted from the first line
+
+	 records are quote-aware, a line break in a quoted field does not end a record.
+`
+
+	Sample = `examples:
+	 gsv sample a.txt                  // header and 20 records (default) chosen at random, in file order
+	 gsv sample -l 100 --seed 7 a.txt  // 100 records, the same seed gives the same sample
+	 gsv sample -n a.txt               // no header
+
+	 with a fresh index, sampled records are read by seeking near each of them,
+	 otherwise the file is read once by reservoir sampling.
+`
+
+	Tail = `examples:
+	 gsv tail a.txt         // header and last 20 records (default), read backwards from the end of file
+	 gsv tail -l 50 a.txt   // header and last 50 records
+	 gsv tail -n a.txt      // no header, last 20 records
+
+	 records are found by counting quotes backwards, a stray quote (see gsv lint) is not detected,
+	 and the records after it may be merged or split. gsv slice reads forwards, ending a record with a stray quote at its line.
 `
 
 	Cat = `examples:
@@ -31,22 +150,128 @@ const (
 	 gsv partition -c 1 a.txt                     // partition by second column
 	 gsv partition -s , a.txt                     // sep ,
 	 gsv partition -s \t a.txt                    // sep \t
-	 gsv partition -summary a.txt                 // generate a summary file
+	 gsv partition -c 0,1 a.txt                   // partition by first and second columns
+	 gsv partition -c year,month a.txt            // partition by columns year and month
+	 gsv partition -c year,month --hive a.txt     // nested directories as year=2024/month=03/part.txt
+	 gsv partition -k "substr(city,0,3)" a.txt    // partition by first 3 characters of column city
+	 gsv partition -k "date(ts,'month')" a.txt    // partition by month of column ts, e.g., 2024-03
+	 gsv partition -k "bucket(amount,100)" a.txt  // partition by ranges of width 100 of column amount, e.g., 200 for 250
+	 gsv partition -k "date(ts,'year'),city" --hive a.txt // nested directories as ts_year=2024/city=beijing/part.txt
+	                                              // hive values are case-sensitive, A and a share a directory on case-insensitive file systems
+
+	 key expressions:
+	 column                  column by name, index or generated name, e.g., city, 0 or col1
+	 substr(column,start,n)  n characters from start (from 0)
+	 date(column,unit)       unit is year, month, day or hour, unparsable dates are empty
+	 bucket(column,width)    numeric range as its lower bound, floor(value/width)*width, non-numeric values are empty
+	 gsv partition --naming value a.txt           // files named by sanitized column values, e.g., beijing.txt
+	 gsv partition --naming value+hash a.txt      // files named by values and a short hash, e.g., beijing-1a2b3c4d.txt
+	 gsv partition -m 200 a.txt                   // cache at most 200MB rows before writing (default 1024)
+	 gsv partition --max-open-files 64 a.txt      // keep at most 64 partition files open (default 256)
+	 gsv partition -summary a.txt                 // generate a summary file, a manifest file is always written in the output directory
 	 gsv partition -n -c 1 -s , -summary a.txt    // all options
 	 gsv partition --help                         // help info 
 `
 
+	Split = `examples:
+	 gsv split -r 100000 a.txt                // chunks of 100000 rows, header is repeated in each chunk
+	 gsv split --size 100 a.txt               // chunks of about 100MB
+	 gsv split -c user_id -k 8 a.txt          // 8 parts by hash of column user_id, rows of a user are in the same part
+	 gsv split -c 0 -k 8 a.txt                // 8 parts by hash of first column
+	 gsv split -r 100000 -p out/chunk_ a.txt  // files named out/chunk_0001.txt, out/chunk_0002.txt, ...
+	 gsv split --ratio 0.8,0.1,0.1 --seed 42 a.txt           // train, valid and test files by hash of row content
+	 gsv split --ratio 0.8,0.2 --stratify label a.txt        // train and test files, keep proportions of labels
+	                                                         // rows moved to keep proportions depend on row order
+	 gsv split --ratio 0.8,0.2 --group user_id a.txt         // rows of a user are in the same file
+	 gsv split -n -s \t -r 100000 a.txt       // no header, tab separator
+	 gsv split --help                         // help info on all flags
+`
+
 	Stats = `examples:
 	 gsv stats a.txt           // has header, separator "," (default)
 	 gsv stats -n a.txt        // no header
 	 gsv stats -s \t a.txt     // tab separator
+	 gsv stats --trim --nfc a.txt   // trim spaces and apply unicode NFC normalization to values
+	 gsv stats -b 0 a.txt      // statistics for each value of first column, one table per group
+	 gsv stats -b 0,2 a.txt    // statistics for each combination of first and third columns
+	 gsv stats -b 0 -o a.txt   // save group statistics in long format to "a-stats-current-time.txt"
+	 gsv stats -b 0 --max-groups 5000 a.txt   // allow up to 5000 groups (default 1000)
+	 gsv stats --mmap a.txt    // read by memory mapping, rows are views of the file without copy
+	 gsv stats --ragged skip --bad-rows bad.txt a.txt
+	                           // drop rows of unequal length, and write them with record numbers to bad.txt
 	 gsv stats --help          // help info
 `
 
+	GroupBy = `examples:
+	 gsv groupby -k 0 a.txt                                // row count for each value of first column (default)
+	 gsv groupby -k region,product -a "sum(amount),mean(price)" a.txt
+	                                                       // group by columns region and product
+	 gsv groupby -k 0,1 -a "count(),count_distinct(3)" a.txt
+	                                                       // columns can be referred to by index
+	 gsv groupby -n -s \t -k 0 -a "min(2),max(2)" a.txt    // no header, tab separator
+	 gsv groupby -k 0 -a "sum(2)" -o a.txt                 // save result to "a-groupby-current-time.txt"
+	 gsv groupby -k 0 --max-groups 100000 a.txt           // spill partial results to disk beyond 100000 groups
+	 gsv groupby --help                                    // help info
+
+	 aggregation syntax:
+	 count():              number of rows
+	 count(col):           number of not-null values
+	 count_distinct(col):  number of unique not-null values
+	 sum(col):             sum of numeric values
+	 mean(col):            mean of numeric values, also avg(col)
+	 min(col), max(col):   numeric order when all values are numeric, otherwise lexical order
+
+	 NOTE: 1. null values (default to "", NA, Na, na, Null, NULL, see --null-values) are skipped in aggregations.
+	       2. after spilling to disk, groups are sorted within each spill bucket only.
+`
+
+	Histogram = `examples:
+	 gsv histogram a.txt                    // first column, 10 equal width bins between min and max (default)
+	 gsv histogram -c price -b 20 a.txt     // column price, 20 bins
+	 gsv histogram -c 2 -e 0,10,20,50 a.txt // explicit bin edges
+	 gsv histogram -c 2 -q -b 4 a.txt       // quartile bins with (nearly) equal number of values
+	 gsv histogram -c 2 -o a.txt            // save histogram to "a-histogram-current-time.txt"
+	 gsv histogram --help                   // help info
+
+	 output fields:
+	 Bin,           Count,  Percent,  Histogram
+	 [0, 10),          40,    40.00,  ##################################################
+	 [10, 20]          20,    20.00,  #########################
+	 > 20,             40,    40.00,  ##################################################
+
+	 bins are [a, b), the last bin is [a, b].
+	 values out of explicit edges are counted in "< a" and "> b" rows.
+`
+
+	Pivot = `examples:
+	 gsv pivot -r 0 -c 1 a.txt                          // crosstab count of first column by second column (default)
+	 gsv pivot -r region -c month -p row a.txt          // crosstab with percentages of row total
+	 gsv pivot -r region -c month -p all a.txt          // crosstab with percentages of grand total
+	 gsv pivot -r region -c month -v amount -a sum a.txt
+	                                                    // sum of amount for each region and month
+	 gsv pivot -r 0 -c 1 -v 2 -a mean -o a.txt          // save result to "a-pivot-current-time.txt"
+	 gsv pivot --help                                   // help info
+
+	 output fields:
+	 region \ month,  01,  02,  (total)
+	 east,            10,  20,       30
+	 west,             5,   5,       10
+	 (total),         15,  25,       40
+
+	 aggregation: count (default), sum, mean, min, max, count_distinct.
+	 count is a crosstab of row key by column key, the value column is not used.
+	 percent option (row, col, all) is only available in count mode.
+`
+
 	Frequency = `output fields:
-	 Col,  Value,  Count
-	 col_1,    a,     10
-	 col_1,    b,     20
+	 Col,     Value,  Count,  Percent,  Cumulative_percent
+	 col_1,       b,     20,    50.00,               50.00
+	 col_1,       a,     10,    25.00,               75.00
+	 col_1,  (null),      6,    15.00,               90.00
+	 col_1, (other),      4,    10.00,              100.00
+
+	 null values (default to "", NA, Na, na, Null, NULL, see --null-values) are counted in the (null) bucket,
+	 values dropped by the limit option are summarized in the (other) row.
 
 	 examples:
 	 gsv frequency a.txt           // first column, has header, separator "," (default)
@@ -57,9 +282,30 @@ const (
 	 gsv frequency -c 0,1 a.txt    // frequency table on first and second columns
 	 gsv frequency -l 10 a.txt     // keep top 10 records
 	 gsv frequency -a a.txt        // frequency table in ascending order, default to descending
+	 gsv frequency --sort value a.txt        // sort by value in lexical order, default to count
+	 gsv frequency --sort numeric -a a.txt   // sort by value in ascending numeric order
 	 gsv frequency -o a.txt        // Print the frequency table to output file named "a-current-time.txt"
+	 gsv frequency -c 0,1 --combine a.txt   // frequency of value combinations of first and second columns
+	 gsv frequency --trim --ignore-case a.txt   // count "Foo", "foo " and "FOO" as the same value "foo"
+	 gsv frequency --null-values ",-,N/A" a.txt // null values are "", "-" and "N/A"
+	 gsv frequency -c 2 --bin 10 --sort numeric -a a.txt  // bucket numeric values into 10 bins, sorted by bin
+	 gsv frequency -m 512 a.txt    // memory budget 512MB (default 1024MB), exact counts spill to disk beyond it
+	 gsv frequency --approx a.txt  // approximate counts of most frequent values within memory budget
+	 gsv frequency --mmap a.txt    // read by memory mapping, rows are views of the file without copy
+	 gsv frequency --ragged skip a.txt  // drop rows of unequal length, pad (default), truncate, skip or error
 	 gsv frequency --help          // help info
 
+	 approximate counts (--approx):
+	 a count-min sketch and a space-saving heavy hitters table are kept for each column, or for the combination,
+	 sharing the memory budget. the sketch overcounts by at most 0.01% of total, or more on a small budget.
+	 an extra error column is shown, the true count is within [count-error, count].
+	 for values not in the table, counts are overestimated by at most the printed bound with 99% probability.
+
+	 combined output fields (--combine):
+	 col_1, col_2, count
+	     a,     x,    10
+	     b,     x,     5
+
 	 column selection syntax:
 	 '1,2':   cols [1,2]
 	 '1-3,6': cols [1,2,3,6]
@@ -76,6 +322,8 @@ const (
 	 gsv select -f 0=abc -o a.txt                    // save result to a-select-current-time.txt
 	 gsv select -n -s \t -f 0=abc -c 0,1,2 -o a.txt  // all options
 	 gsv select -c 0,1 -o a.txt                      // NO filter, only to select columns
+	 gsv select -f 0=abc --mmap a.txt                // read by memory mapping
+	 gsv select -f 0=abc --ragged error a.txt        // stop at the first row of unequal length
 	 gsv select --help                               // help info on other options
 	
 	 column filter syntax:
@@ -84,6 +332,7 @@ const (
 	 -f '1=5':         same as pre command, second column equal to number 5.0
 	 -f '0=abc&1=5.0': first column is 'abc' AND second column is 5.0
 	 -f '0=abc|1=5.0': first column is 'abc' OR second column is 5.0
+	 --ignore-case -f '0=abc': first column is 'abc', 'ABC', 'Abc', ...
 	
 	 NOTE: 1. more complex syntax with brackets 
 	          such as '(0=abc|1=5.0)&c=1' is not supported.
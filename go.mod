@@ -6,6 +6,7 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/schollz/progressbar/v2 v2.15.0
 	github.com/urfave/cli v1.22.5
+	golang.org/x/text v0.3.7
 )
 
 require (
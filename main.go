@@ -9,6 +9,55 @@ import (
 	"os"
 )
 
+// value normalization flags shared by commands counting, guessing types or filtering values
+var normalizeFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "trim",
+		Usage: "Trim leading and trailing spaces of values",
+	},
+	cli.BoolFlag{
+		Name:  "ignore-case",
+		Usage: "Compare values case-insensitively, values are lower cased",
+	},
+	cli.BoolFlag{
+		Name:  "nfc",
+		Usage: "Apply unicode normalization form NFC to values",
+	},
+	cli.StringFlag{
+		Name:  "null-values",
+		Usage: "Comma separated list of null values, the first one is empty string by default",
+		Value: utility.DefaultNullValues,
+	},
+}
+
+// memory mapped reading, shared by commands reading whole files in parallel
+var mmapFlag = cli.BoolFlag{
+	Name:  "mmap",
+	Usage: "Read file by memory mapping, rows are views of the file without copy. Ignored where mmap is not supported",
+}
+
+// ragged row flags, shared by commands reading rows of files
+var raggedFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "ragged",
+		Usage: "Policy on rows whose number of fields differs from the first row: error, skip, pad (default) or truncate",
+		Value: utility.RaggedPad,
+	},
+	cli.StringFlag{
+		Name:  "bad-rows",
+		Usage: "Write ragged rows to a file, each row is prefixed with its line number",
+	},
+}
+
+func raggedOption(c *cli.Context, sep string) (*utility.Ragged, error) {
+	return utility.NewRagged(c.String("ragged"), c.String("bad-rows"), sep)
+}
+
+func normalizeOption(c *cli.Context) error {
+	utility.SetNormalization(c.Bool("trim"), c.Bool("ignore-case"), c.Bool("nfc"), c.String("null-values"))
+	return nil
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "gsv"
@@ -22,16 +71,73 @@ func main() {
 			Description: cmd_desc.Head,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
 				n := c.Int("l")
-				cmd.Head(path, n)
+				table := c.Bool("t")
+				width := c.Int("w")
+				cmd.Head(path, header, sep, n, table, width)
 				return nil
 			},
 			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names, used by table",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator, used by table",
+					Value: ",",
+				},
 				cli.IntFlag{
 					Name:  "length, l",
 					Usage: "Number of records to display",
 					Value: 20,
 				},
+				cli.BoolFlag{
+					Name:  "table, t",
+					Usage: "Show records in a table, the header is separated",
+				},
+				cli.IntFlag{
+					Name:  "width, w",
+					Usage: "Max width of a table cell, longer values are truncated",
+					Value: cmd.CellWidth,
+				},
+			},
+		},
+		{
+			Name:        "view",
+			Usage:       "View records in a table page by page, with scrolling and frozen columns",
+			Description: cmd_desc.View,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
+				rows := c.Int("l")
+				width := c.Int("w")
+				cmd.View(path, header, sep, rows, width)
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator",
+					Value: ",",
+				},
+				cli.IntFlag{
+					Name:  "length, l",
+					Usage: "Number of records of a page",
+					Value: cmd.ViewPageRows,
+				},
+				cli.IntFlag{
+					Name:  "width, w",
+					Usage: "Max width of a table cell, longer values are truncated",
+					Value: cmd.CellWidth,
+				},
 			},
 		},
 		{
@@ -40,26 +146,126 @@ func main() {
 			Description: cmd_desc.Header,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
+				header := !c.Bool("n")
 				sep := utility.SepArg(c.String("s"))
-				cmd.Header(path, sep)
+				rows := c.Int("r")
+				samples := c.Int("samples")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Header(path, header, sep, rows, samples, ragged)
 				return nil
 			},
-			Flags: []cli.Flag{
+			Before: normalizeOption,
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names, columns are named by position",
+				},
 				cli.StringFlag{
 					Name:  "sep, s",
 					Usage: "File separator",
 					Value: ",",
 				},
-			},
+				cli.IntFlag{
+					Name:  "rows, r",
+					Usage: "Number of first rows read for types, null rates and sample values",
+					Value: cmd.HeaderRows,
+				},
+				cli.IntFlag{
+					Name:  "samples",
+					Usage: "Number of distinct sample values of a column",
+					Value: cmd.HeaderSamples,
+				},
+			}, append(normalizeFlags, raggedFlags...)...),
 		},
 		{
 			Name:        "count",
 			Usage:       "Count total lines of file",
 			Description: cmd_desc.Count,
+			Before:      normalizeOption,
+			Action: func(c *cli.Context) error {
+				paths := c.Args()
+				header := !c.Bool("n")
+				mmap := c.Bool("mmap")
+				if c.IsSet("filter") || c.IsSet("by") {
+					sep := utility.SepArg(c.String("s"))
+					ragged, err := raggedOption(c, sep)
+					if err != nil {
+						fmt.Println(err.Error())
+						return nil
+					}
+					cmd.CountFilter(paths.First(), header, sep, c.String("filter"), c.String("by"), mmap, ragged)
+					return nil
+				}
+				records := c.Bool("records")
+				cmd.Count(paths, header, mmap, records)
+				return nil
+			},
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.BoolFlag{
+					Name:  "records, r",
+					Usage: "Count quote-aware CSV records instead of lines, a line break in a quoted field does not end a record",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator, used by filter and by",
+					Value: ",",
+				},
+				cli.StringFlag{
+					Name:  "filter, f",
+					Usage: "Count records satisfying filter criterion, same syntax as select",
+				},
+				cli.StringFlag{
+					Name:  "by, b",
+					Usage: "Count records by values of a column, referred to by header name or index",
+				},
+				mmapFlag,
+			}, append(normalizeFlags, raggedFlags...)...),
+		},
+		{
+			Name:        "index",
+			Usage:       "Build a sidecar index of record offsets, used by count and slice",
+			Description: cmd_desc.Index,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				step := c.Int("step")
+				cmd.Index(path, step)
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "step",
+					Usage: "Keep the offset of every N records",
+					Value: utility.IndexStep,
+				},
+			},
+		},
+		{
+			Name:        "slice",
+			Usage:       "Show records in a range of record numbers",
+			Description: cmd_desc.Slice,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
 				header := !c.Bool("n")
-				cmd.Count(path, header)
+				start := c.Int("start")
+				end := c.Int("end")
+				if c.IsSet("len") {
+					if c.IsSet("end") {
+						fmt.Print("Set either end or len. Try command 'gsv slice --help'.")
+						return nil
+					}
+					end = start + c.Int("len")
+				} else if !c.IsSet("end") {
+					end = start + 20
+				}
+				cmd.Slice(path, header, start, end)
 				return nil
 			},
 			Flags: []cli.Flag{
@@ -67,6 +273,70 @@ func main() {
 					Name:  "no-header, n",
 					Usage: "When set, the first row will NOT be interpreted as column names",
 				},
+				cli.IntFlag{
+					Name:  "start",
+					Usage: "First record, counted from 0",
+				},
+				cli.IntFlag{
+					Name:  "end",
+					Usage: "Record after the last one, default to start + 20",
+				},
+				cli.IntFlag{
+					Name:  "len, l",
+					Usage: "Number of records, instead of end",
+				},
+			},
+		},
+		{
+			Name:        "sample",
+			Usage:       "Show records chosen uniformly at random",
+			Description: cmd_desc.Sample,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				n := c.Int("l")
+				seed := c.Int64("seed")
+				cmd.Sample(path, header, n, seed)
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.IntFlag{
+					Name:  "length, l",
+					Usage: "Number of records to sample",
+					Value: 20,
+				},
+				cli.Int64Flag{
+					Name:  "seed",
+					Usage: "Seed of random sampling, the same seed gives the same sample",
+					Value: 1,
+				},
+			},
+		},
+		{
+			Name:        "tail",
+			Usage:       "Show tail n records of file",
+			Description: cmd_desc.Tail,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				n := c.Int("l")
+				cmd.Tail(path, header, n)
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.IntFlag{
+					Name:  "length, l",
+					Usage: "Number of records to display",
+					Value: 20,
+				},
 			},
 		},
 		{
@@ -94,25 +364,40 @@ func main() {
 		},
 		{
 			Name:        "partition",
-			Usage:       "Partitions CSV file into chunks based on a column value",
+			Usage:       "Partitions CSV file into chunks based on column values",
 			Description: cmd_desc.Partition,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
 				header := !c.Bool("n")
-				column := c.Int("c")
+				column := c.String("c")
+				key := c.String("key")
 				sep := utility.SepArg(c.String("s"))
 				summary := c.Bool("summary")
-				cmd.Partition(path, header, column, sep, summary)
+				hive := c.Bool("hive")
+				naming := c.String("naming")
+				memory := c.Int("memory")
+				maxOpenFiles := c.Int("max-open-files")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Partition(path, header, column, key, sep, summary, hive, naming, memory, maxOpenFiles, ragged)
 				return nil
 			},
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "no-header, n",
 					Usage: "When set, the first row will NOT be interpreted as column names",
 				},
-				cli.IntFlag{
+				cli.StringFlag{
 					Name:  "column, c",
-					Usage: "Partition by which column",
+					Usage: "Partition by which columns, by name or index, e.g., 0 or year,month",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "key, k",
+					Usage: "Partition by key expressions instead of columns, e.g., substr(city,0,3) or date(ts,\"month\"),bucket(amount,100)",
 				},
 				cli.StringFlag{
 					Name:  "sep, s",
@@ -123,20 +408,179 @@ func main() {
 					Name:  "summary",
 					Usage: "Generate a summary file tabling line counts for each column value",
 				},
+				cli.BoolFlag{
+					Name:  "hive",
+					Usage: "Write partitions into hive-style nested directories, e.g., year=2024/month=03/part.csv",
+				},
+				cli.StringFlag{
+					Name:  "naming",
+					Usage: "Naming of partition files, value, hash or value+hash",
+					Value: "hash",
+				},
+				cli.IntFlag{
+					Name:  "memory, m",
+					Usage: "Memory budget in MB for rows cached before writing",
+					Value: 1024,
+				},
+				cli.IntFlag{
+					Name:  "max-open-files",
+					Usage: "Max number of partition files kept open, each with a 64KB write buffer",
+					Value: 256,
+				},
+			}, raggedFlags...),
+		},
+		{
+			Name:        "split",
+			Usage:       "Split CSV file into chunks of rows, chunks of size, or parts by hash of a column",
+			Description: cmd_desc.Split,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
+				rows := c.Int("rows")
+				size := c.Int("size")
+				column := c.String("c")
+				parts := c.Int("parts")
+				ratio := c.String("ratio")
+				seed := c.Int64("seed")
+				stratify := c.String("stratify")
+				group := c.String("group")
+				prefix := c.String("prefix")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Split(path, header, sep, rows, size, column, parts, ratio, seed, stratify, group, prefix, ragged)
+				return nil
 			},
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator",
+					Value: ",",
+				},
+				cli.IntFlag{
+					Name:  "rows, r",
+					Usage: "Split into chunks of N rows",
+				},
+				cli.IntFlag{
+					Name:  "size",
+					Usage: "Split into chunks of about N MB",
+				},
+				cli.StringFlag{
+					Name:  "column, c",
+					Usage: "Split into parts by hash of a column, by name or index, rows with the same value are in the same part",
+				},
+				cli.IntFlag{
+					Name:  "parts, k",
+					Usage: "Number of parts when splitting by column",
+					Value: 10,
+				},
+				cli.StringFlag{
+					Name:  "ratio",
+					Usage: "Split into parts by ratios, e.g., 0.8,0.1,0.1 for train, valid and test files",
+				},
+				cli.Int64Flag{
+					Name:  "seed",
+					Usage: "Seed of hash when splitting by ratio, the same seed gives the same split",
+					Value: 1,
+				},
+				cli.StringFlag{
+					Name:  "stratify",
+					Usage: "Keep proportions of labels in this column for each part when splitting by ratio, rows moved to keep them depend on row order",
+				},
+				cli.StringFlag{
+					Name:  "group",
+					Usage: "Keep rows with the same value in this column in the same part when splitting by ratio",
+				},
+				cli.StringFlag{
+					Name:  "prefix, p",
+					Usage: "Prefix of split files, followed by file number and extension, default to file-split-current-time-",
+				},
+			}, raggedFlags...),
 		},
 		{
 			Name:        "stats",
 			Usage:       "Show statistics (e.g., min, max, average, unique count, null) on every column",
 			Description: cmd_desc.Stats,
+			Before:      normalizeOption,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
 				header := !c.Bool("n")
 				sep := utility.SepArg(c.String("s"))
-				cmd.Stats(path, header, sep)
+				var by []int
+				if c.String("by") != "" {
+					col, err := utility.ParseColArg(c.String("by"))
+					if err != nil || col.All {
+						fmt.Println("group-by column syntax error.")
+						return nil
+					}
+					by = col.Include
+				}
+				out := c.Bool("o")
+				maxGroups := c.Int("max-groups")
+				mmap := c.Bool("mmap")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Stats(path, header, sep, by, out, maxGroups, mmap, ragged)
 				return nil
 			},
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator",
+					Value: ",",
+				},
+				cli.StringFlag{
+					Name:  "by, b",
+					Usage: "Group-by columns, statistics are computed for each group, e.g., 0 or 0,2",
+				},
+				cli.IntFlag{
+					Name:  "max-groups",
+					Usage: "Maximum number of groups, abort when exceeded. Set to '0' to disable a limit",
+					Value: 1000,
+				},
+				cli.BoolFlag{
+					Name:  "output, o",
+					Usage: "Print the group statistics to an output file in long format, instead of stdout",
+				},
+				mmapFlag,
+			}, append(normalizeFlags, raggedFlags...)...),
+		},
+		{
+			Name:        "groupby",
+			Usage:       "Aggregate columns (e.g., sum, mean, count, count_distinct, min, max) for each group",
+			Description: cmd_desc.GroupBy,
+			Before:      normalizeOption,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
+				key := c.String("k")
+				agg := c.String("a")
+				out := c.Bool("o")
+				maxGroups := c.Int("max-groups")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.GroupBy(path, header, sep, key, agg, out, maxGroups, ragged)
+				return nil
+			},
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "no-header, n",
 					Usage: "When set, the first row will NOT be interpreted as column names",
@@ -146,12 +590,156 @@ func main() {
 					Usage: "File separator",
 					Value: ",",
 				},
+				cli.StringFlag{
+					Name:  "key, k",
+					Usage: "Group-by columns, by name or index, e.g., region,product or 0,1",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "agg, a",
+					Usage: "Aggregations, e.g., \"sum(amount),mean(price),count()\", see syntax in description",
+					Value: "count()",
+				},
+				cli.IntFlag{
+					Name:  "max-groups",
+					Usage: "Maximum number of groups kept in memory, partial results are spilled to disk when exceeded",
+					Value: 1000000,
+				},
+				cli.BoolFlag{
+					Name:  "output, o",
+					Usage: "Print the aggregation table to an output file, instead of stdout",
+				},
+			}, append(normalizeFlags, raggedFlags...)...),
+		},
+		{
+			Name:        "histogram",
+			Usage:       "Show histogram of a numeric column",
+			Description: cmd_desc.Histogram,
+			Before:      normalizeOption,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
+				col := c.String("c")
+				bins := c.Int("bins")
+				edges := c.String("edges")
+				quantile := c.Bool("quantile")
+				out := c.Bool("o")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Histogram(path, header, sep, col, bins, edges, quantile, out, ragged)
+				return nil
+			},
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator",
+					Value: ",",
+				},
+				cli.StringFlag{
+					Name:  "col, c",
+					Usage: "Numeric column, by name or index",
+					Value: "0",
+				},
+				cli.IntFlag{
+					Name:  "bins, b",
+					Usage: "Number of bins",
+					Value: 10,
+				},
+				cli.StringFlag{
+					Name:  "edges, e",
+					Usage: "Explicit increasing bin edges, e.g., 0,10,20,50. Override bins option",
+				},
+				cli.BoolFlag{
+					Name:  "quantile, q",
+					Usage: "Quantile bins with (nearly) equal number of values, instead of equal width bins",
+				},
+				cli.BoolFlag{
+					Name:  "output, o",
+					Usage: "Print the histogram to an output file, instead of stdout",
+				},
+			}, append(normalizeFlags, raggedFlags...)...),
+		},
+		{
+			Name:        "pivot",
+			Usage:       "Pivot table of row key by column key aggregates, or crosstab counts",
+			Description: cmd_desc.Pivot,
+			Before:      normalizeOption,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
+				row := c.String("r")
+				col := c.String("c")
+				val := c.String("v")
+				agg := c.String("a")
+				percent := c.String("p")
+				out := c.Bool("o")
+				maxCols := c.Int("max-cols")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Pivot(path, header, sep, row, col, val, agg, percent, out, maxCols, ragged)
+				return nil
 			},
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator",
+					Value: ",",
+				},
+				cli.StringFlag{
+					Name:  "row, r",
+					Usage: "Row key column, by name or index",
+					Value: "0",
+				},
+				cli.StringFlag{
+					Name:  "col, c",
+					Usage: "Column key column, by name or index",
+					Value: "1",
+				},
+				cli.StringFlag{
+					Name:  "value, v",
+					Usage: "Value column to aggregate, by name or index",
+				},
+				cli.StringFlag{
+					Name:  "agg, a",
+					Usage: "Aggregation: count, sum, mean, min, max or count_distinct",
+					Value: "count",
+				},
+				cli.StringFlag{
+					Name:  "percent, p",
+					Usage: "Show counts as percentages of row, col or all total, only in count mode",
+				},
+				cli.IntFlag{
+					Name:  "max-cols",
+					Usage: "Maximum number of column keys. Set to '0' to disable a limit",
+					Value: 100,
+				},
+				cli.BoolFlag{
+					Name:  "output, o",
+					Usage: "Print the pivot table to an output file, instead of stdout",
+				},
+			}, append(normalizeFlags, raggedFlags...)...),
 		},
 		{
 			Name:        "frequency",
 			Usage:       "Show frequency tables",
 			Description: cmd_desc.Frequency,
+			Before:      normalizeOption,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
 				header := !c.Bool("n")
@@ -164,10 +752,21 @@ func main() {
 				out := c.Bool("o")
 				ascending := c.Bool("a")
 				limit := c.Int("l")
-				cmd.Frequency(path, header, sep, col, out, ascending, limit)
+				combine := c.Bool("combine")
+				sortBy := c.String("sort")
+				approx := c.Bool("approx")
+				memory := c.Int("memory")
+				bin := c.Int("bin")
+				mmap := c.Bool("mmap")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Frequency(path, header, sep, col, out, ascending, limit, combine, sortBy, approx, memory, bin, mmap, ragged)
 				return nil
 			},
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "no-header, n",
 					Usage: "When set, the first row will NOT be interpreted as column names",
@@ -184,7 +783,7 @@ func main() {
 				},
 				cli.IntFlag{
 					Name:  "limit, l",
-					Usage: "Limit the frequency table to the N most common items. Set to '0' to disable a limit",
+					Usage: "Limit the frequency table to the first N items, the rest are summarized as (other). Set to '0' to disable a limit",
 					Value: 50,
 				},
 				cli.BoolFlag{
@@ -193,14 +792,38 @@ func main() {
 				},
 				cli.BoolFlag{
 					Name:  "ascending, a",
-					Usage: "Frequency table in ascending order, default to descending",
+					Usage: "Frequency table in ascending order of the sort key, default to descending",
 				},
-			},
+				cli.BoolFlag{
+					Name:  "combine",
+					Usage: "Count combinations of values in selected columns, instead of each column independently",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Usage: "Sort frequency table by count, value (lexical order) or numeric (numeric order of value)",
+					Value: "count",
+				},
+				cli.BoolFlag{
+					Name:  "approx",
+					Usage: "Approximate counts of most frequent values within memory budget, for high-cardinality columns",
+				},
+				cli.IntFlag{
+					Name:  "memory, m",
+					Usage: "Memory budget in MB. Exact counts are spilled to disk when exceeded. Set to '0' to disable a limit, approximate counting then uses the default budget",
+					Value: cmd.FreqMemory,
+				},
+				cli.IntFlag{
+					Name:  "bin",
+					Usage: "Bucket values of numeric columns into N equal width bins between min and max, instead of counting raw values",
+				},
+				mmapFlag,
+			}, append(normalizeFlags, raggedFlags...)...),
 		},
 		{
 			Name:        "select",
 			Usage:       "Select rows and columns based on filters",
 			Description: cmd_desc.Select,
+			Before:      normalizeOption,
 			Action: func(c *cli.Context) error {
 				path := c.Args().First()
 				header := !c.Bool("n")
@@ -212,10 +835,16 @@ func main() {
 					return nil
 				}
 				out := c.Bool("o")
-				cmd.Select(path, header, sep, filter, col, out)
+				mmap := c.Bool("mmap")
+				ragged, err := raggedOption(c, sep)
+				if err != nil {
+					fmt.Println(err.Error())
+					return nil
+				}
+				cmd.Select(path, header, sep, filter, col, out, mmap, ragged)
 				return nil
 			},
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "no-header, n",
 					Usage: "When set, the first row will NOT be interpreted as column names",
@@ -238,12 +867,92 @@ func main() {
 					Name:  "output, o",
 					Usage: "Print the frequency table to an output file, instead of stdout",
 				},
+				mmapFlag,
+			}, append(normalizeFlags, raggedFlags...)...),
+		},
+		{
+			Name:        "schema",
+			Usage:       "Infer schema of file in json",
+			Description: cmd_desc.Schema,
+			Subcommands: []cli.Command{
+				{
+					Name:        "infer",
+					Usage:       "Infer names, types, nullability, min and max, enum candidates and patterns of columns",
+					Description: cmd_desc.Schema,
+					Action: func(c *cli.Context) error {
+						path := c.Args().First()
+						header := !c.Bool("n")
+						sep := utility.SepArg(c.String("s"))
+						ragged, err := raggedOption(c, sep)
+						if err != nil {
+							fmt.Println(err.Error())
+							return nil
+						}
+						cmd.SchemaInfer(path, header, sep, ragged)
+						return nil
+					},
+					Flags: append([]cli.Flag{
+						cli.BoolFlag{
+							Name:  "no-header, n",
+							Usage: "When set, the first row will NOT be interpreted as column names",
+						},
+						cli.StringFlag{
+							Name:  "sep, s",
+							Usage: "File separator",
+							Value: ",",
+						},
+					}, raggedFlags...),
+				},
+			},
+		},
+		{
+			Name:        "validate",
+			Usage:       "Validate file against a schema, exit with non-zero status on violations",
+			Description: cmd_desc.Validate,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				schema := c.String("schema")
+				if !cmd.Validate(path, schema) {
+					return cli.NewExitError("", 1)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "schema",
+					Usage: "Schema file in json, inferred by 'gsv schema infer'",
+				},
+			},
+		},
+		{
+			Name:        "lint",
+			Usage:       "Check file for common CSV problems, exit with non-zero status on problems",
+			Description: cmd_desc.Lint,
+			Action: func(c *cli.Context) error {
+				path := c.Args().First()
+				header := !c.Bool("n")
+				sep := utility.SepArg(c.String("s"))
+				if !cmd.Lint(path, header, sep) {
+					return cli.NewExitError("", 1)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-header, n",
+					Usage: "When set, the first row will NOT be interpreted as column names",
+				},
+				cli.StringFlag{
+					Name:  "sep, s",
+					Usage: "File separator",
+					Value: ",",
+				},
 			},
 		},
 	}
 
 	app.CommandNotFound = func(c *cli.Context, command string) {
-		fmt.Printf("No matching command '%s', available commands are ['head', 'header', 'count', 'cat', 'frequency', 'partition', 'select', 'stats']", command)
+		fmt.Printf("No matching command '%s', available commands are ['head', 'header', 'count', 'index', 'lint', 'slice', 'cat', 'frequency', 'groupby', 'histogram', 'partition', 'pivot', 'sample', 'select', 'split', 'schema', 'stats', 'tail', 'validate', 'view']", command)
 	}
 
 	err := app.Run(os.Args)